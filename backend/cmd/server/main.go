@@ -7,16 +7,32 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"marko-backend/internal/filesystem"
+	"marko-backend/internal/gitvault"
 	"marko-backend/internal/handlers"
+	"marko-backend/internal/models"
 	"marko-backend/internal/search"
+	"marko-backend/internal/storage"
+	"marko-backend/internal/watcher"
 )
 
 func main() {
 	seedPtr := flag.Int("seed", 0, "Number of dummy notes to generate")
+	storagePtr := flag.String("storage", "fs", "Storage backend: fs, git, memory or sqlite")
+	reindexPtr := flag.Bool("reindex", false, "Rebuild the whole search index on startup instead of syncing changes")
+	watchPtr := flag.Bool("watch", true, "Watch the data directory and reindex notes edited outside the API")
+	pollPtr := flag.Bool("watch-poll", false, "Use polling instead of inotify for -watch")
+	retentionPtr := flag.Duration("trash-retention", 30*24*time.Hour, "Permanently delete trashed notes after this long (0 keeps them forever)")
+	cachePtr := flag.Bool("metadata-cache", true, "Persist the note metadata cache under .cache/ so listing is fast after a restart")
+	recencyPtr := flag.Float64("search-recency", 0, "Boost recently updated notes in search results by up to this factor (0 disables)")
+	var gitConfig gitvault.Config
+	flag.StringVar(&gitConfig.AuthorName, "git-author-name", "Marko", "Commit author name for -storage git")
+	flag.StringVar(&gitConfig.AuthorEmail, "git-author-email", "marko@localhost", "Commit author email for -storage git")
+	flag.StringVar(&gitConfig.MessageTemplate, "git-message", gitvault.DefaultMessage, "Commit message template for -storage git")
 	flag.Parse()
 
 	// Initialize Store
@@ -25,34 +41,50 @@ func main() {
 		dataDir = "../data/notes"
 	}
 
-	store := filesystem.NewStore(dataDir)
+	store, closeStore, err := openStore(*storagePtr, dataDir, gitConfig)
+	if err != nil {
+		log.Fatalf("Failed to open %s storage: %v", *storagePtr, err)
+	}
+	defer closeStore()
+
+	// Both the fs and git stores list notes through a metadata cache
+	if c, ok := store.(interface{ PersistMetadata(string) error }); ok && *cachePtr {
+		if err := c.PersistMetadata(filesystem.MetadataCachePath(dataDir)); err != nil {
+			log.Printf("Warning: Failed to load metadata cache: %v", err)
+		}
+	}
 
 	// Initialize Search
-	searchService, err := search.NewService(dataDir)
+	ranking := search.DefaultRanking
+	ranking.Recency = *recencyPtr
+	searchService, err := search.NewService(dataDir, search.Options{Ranking: ranking})
 	if err != nil {
 		log.Printf("Warning: Failed to initialize search service: %v", err)
 	} else {
 		defer searchService.Close()
 
-		// Initial sync/reindex on startup (simple approach)
-		// strictly speaking we should only do this if requested or if valid index check fails
-		// but for V1 let's accept startup cost or just do it in background
+		// Bring the index up to date in the background. Only notes whose
+		// mtime and content hash changed are reindexed, unless -reindex
+		// asks for a full rebuild.
 		go func() {
-			log.Println("Syncing search index...")
-			notes, err := store.List()
-			if err == nil {
-				// We need full content to index, List() usually optimizes this out.
-				// store.List() currently returns full content?
-				// Checked store.go: "note.Content = "" // Don't return full content in list"
-				// So we need to re-read all.
-				for _, n := range notes {
-					fullNote, err := store.Get(n.ID)
-					if err == nil {
-						searchService.Index(fullNote)
-					}
+			if *reindexPtr {
+				log.Println("Rebuilding search index...")
+				if err := reindexAll(store, searchService); err != nil {
+					log.Printf("Warning: Failed to rebuild search index: %v", err)
+					return
 				}
+				log.Println("Search index rebuilt.")
+				return
 			}
-			log.Println("Search index synced.")
+
+			log.Println("Syncing search index...")
+			stats, err := searchService.Sync(store)
+			if err != nil {
+				log.Printf("Warning: Failed to sync search index: %v", err)
+				return
+			}
+			log.Printf("Search index synced: %d added, %d updated, %d removed, %d unchanged in %s",
+				stats.Added, stats.Updated, stats.Removed, stats.Unchanged, stats.Duration.Round(time.Millisecond))
 		}()
 	}
 
@@ -63,14 +95,43 @@ func main() {
 		return
 	}
 
+	// Keep the index in sync with edits made outside the API (vim, Obsidian, git)
+	if source, ok := store.(watcher.Source); ok && *watchPtr && searchService != nil {
+		w := watcher.New(dataDir, source, searchService, watcher.Options{ForcePolling: *pollPtr})
+		if mode, err := w.Start(); err != nil {
+			log.Printf("Warning: Failed to start file watcher: %v", err)
+		} else {
+			log.Printf("Watching %s for external changes (%s)", dataDir, mode)
+			defer w.Close()
+		}
+	}
+
+	// Purge notes that sat in the trash for longer than -trash-retention
+	if trash, ok := store.(storage.TrashRepository); ok && *retentionPtr > 0 {
+		go sweepTrash(trash, *retentionPtr)
+	}
+
 	noteHandler := handlers.NewNoteHandler(store, searchService)
 
 	mux := http.NewServeMux()
 	mux.Handle("/api/notes", noteHandler)
 	mux.Handle("/api/notes/", noteHandler)
 
+	folderHandler := handlers.NewFolderHandler(store, searchService)
+	mux.Handle("/api/folders", folderHandler)
+	mux.Handle("/api/folders/", folderHandler)
+
+	trashHandler := handlers.NewTrashHandler(store, searchService)
+	mux.Handle("/api/trash", trashHandler)
+	mux.Handle("/api/trash/", trashHandler)
+
 	// Explicit search route
 	mux.HandleFunc("/api/search", noteHandler.Search)
+	mux.HandleFunc("/api/tags", noteHandler.ListTags)
+	mux.HandleFunc("/api/links/broken", noteHandler.BrokenLinks)
+	mux.HandleFunc("/api/graph", noteHandler.Graph)
+	mux.HandleFunc("/api/tasks", noteHandler.ListTasks)
+	mux.HandleFunc("/api/snippets", noteHandler.ListSnippets)
 
 	// Wrap with CORS
 	handler := corsMiddleware(mux)
@@ -81,17 +142,78 @@ func main() {
 	log.Fatal(http.ListenAndServe(":"+port, handler))
 }
 
-func seedNotes(store *filesystem.Store, search *search.Service, count int) {
-	fmt.Println("Clearing existing notes...")
-	if existing, err := store.List(); err == nil {
-		for _, n := range existing {
-			_ = store.Delete(n.ID)
-			if search != nil {
-				_ = search.Delete(n.ID)
-			}
+// openStore builds the NoteRepository selected by the -storage flag.
+// The returned close function releases any resources held by the backend.
+func openStore(kind, dataDir string, gitConfig gitvault.Config) (storage.NoteRepository, func(), error) {
+	switch kind {
+	case "fs", "":
+		return filesystem.NewStore(dataDir), func() {}, nil
+	case "git":
+		repo, err := gitvault.Open(dataDir, gitConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, func() {}, nil
+	case "memory":
+		return storage.NewMemoryRepository(), func() {}, nil
+	case "sqlite":
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			return nil, nil, err
+		}
+		repo, err := storage.NewSQLiteRepository(filepath.Join(dataDir, "notes.db"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, func() { repo.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage backend %q", kind)
+	}
+}
+
+// sweepTrash periodically deletes trashed notes older than retention.
+// It checks once at startup and then every hour (or every retention
+// period, if shorter).
+func sweepTrash(trash storage.TrashRepository, retention time.Duration) {
+	interval := time.Hour
+	if retention < interval {
+		interval = retention
+	}
+	for {
+		n, err := trash.EmptyTrash(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Warning: Failed to sweep trash: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d note(s) from the trash", n)
 		}
+		time.Sleep(interval)
+	}
+}
+
+// reindexAll reads every note in full and rebuilds the search index from scratch.
+func reindexAll(store storage.NoteRepository, search *search.Service) error {
+	list, err := store.List()
+	if err != nil {
+		return err
 	}
 
+	// List() leaves out content, so re-read every note
+	notes := make([]models.Note, 0, len(list))
+	for _, n := range list {
+		fullNote, err := store.Get(n.ID)
+		if err != nil {
+			log.Printf("Failed to read note %s: %v", n.ID, err)
+			continue
+		}
+		notes = append(notes, fullNote)
+	}
+
+	return search.ReindexAll(notes)
+}
+
+func seedNotes(store storage.NoteRepository, search *search.Service, count int) {
+	fmt.Println("Clearing existing notes...")
+	clearNotes(store, search)
+
 	topics := []string{
 		"Go Concurrency Patterns", "React Server Components", "Docker Optimization",
 		"Postgres Indexing", "Microservices Architecture", "Kubernetes Deployment",
@@ -150,11 +272,51 @@ func seedNotes(store *filesystem.Store, search *search.Service, count int) {
 	fmt.Println("Seeding complete with realistic data.")
 }
 
+// clearNotes deletes every note before seeding. Deleting moves notes to the
+// trash, so the trash items it creates are purged right away; items that
+// were in the trash before stay.
+func clearNotes(store storage.NoteRepository, search *search.Service) {
+	existing, err := store.List()
+	if err != nil {
+		return
+	}
+
+	start := time.Now()
+	cleared := make(map[string]bool, len(existing))
+	for _, n := range existing {
+		if err := store.Delete(n.ID); err != nil {
+			continue
+		}
+		cleared[n.ID] = true
+		if search != nil {
+			_ = search.Delete(n.ID)
+		}
+	}
+
+	trash, ok := store.(storage.TrashRepository)
+	if !ok {
+		return
+	}
+	items, err := trash.Trash()
+	if err != nil {
+		log.Printf("Failed to list trash: %v", err)
+		return
+	}
+	for _, item := range items {
+		if cleared[item.NoteID] && !item.DeletedAt.Before(start) {
+			if err := trash.PurgeTrash(item.ID); err != nil {
+				log.Printf("Failed to purge %s from the trash: %v", item.NoteID, err)
+			}
+		}
+	}
+}
+
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*") // Allow all for local dev
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
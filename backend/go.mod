@@ -2,4 +2,4 @@ module marko-backend
 
 go 1.21
 
-require github.com/mattn/go-sqlite3 v1.14.33 // indirect
+require github.com/mattn/go-sqlite3 v1.14.33
@@ -0,0 +1,40 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+
+	want := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got := Unified("old", "new", a, b, 3); got != want {
+		t.Errorf("unexpected diff:\n%s", got)
+	}
+}
+
+func TestUnified_Equal(t *testing.T) {
+	if got := Unified("old", "new", "same\n", "same\n", 3); got != "" {
+		t.Errorf("expected empty diff, got:\n%s", got)
+	}
+}
+
+func TestUnified_FromEmpty(t *testing.T) {
+	want := "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	if got := Unified("old", "new", "", "x\ny\n", 3); got != want {
+		t.Errorf("unexpected diff:\n%s", got)
+	}
+}
@@ -0,0 +1,76 @@
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Hooks around the steps of writeFile, replaced by tests to simulate a
+// full disk or a crash half way through.
+var (
+	writeTemp = func(f *os.File, data []byte) (int, error) { return f.Write(data) }
+	syncFile  = func(f *os.File) error { return f.Sync() }
+	renameFn  = os.Rename
+)
+
+// writeFile replaces the file at path with data so that readers (and the
+// disk after a crash) see either the old or the new content, never a mix:
+// data goes to a temporary file in the same directory, is fsynced, and is
+// renamed over path; the directory is fsynced so the rename itself is
+// durable. An existing file keeps its permissions, new files get perm.
+func writeFile(path string, data []byte, perm fs.FileMode) (err error) {
+	if info, statErr := os.Stat(path); statErr == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(path)
+	// The leading dot keeps the temporary file out of List and the watcher
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	n, err := writeTemp(tmp, data)
+	if err == nil && n < len(data) {
+		err = errors.New("short write")
+	}
+	if err != nil {
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err = syncFile(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = renameFn(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir fsyncs a directory so renames and new entries in it survive a
+// crash. File systems that cannot sync directories (EINVAL) are ignored.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+	return nil
+}
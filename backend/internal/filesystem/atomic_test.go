@@ -0,0 +1,101 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// failAt swaps one step of writeFile for a failing one for the rest of the test.
+func failAt(t *testing.T, step string) {
+	t.Helper()
+	origWrite, origSync, origRename := writeTemp, syncFile, renameFn
+	t.Cleanup(func() { writeTemp, syncFile, renameFn = origWrite, origSync, origRename })
+
+	switch step {
+	case "write":
+		// Half the data reaches the disk, then it is full
+		writeTemp = func(f *os.File, data []byte) (int, error) {
+			n, _ := f.Write(data[:len(data)/2])
+			return n, syscall.ENOSPC
+		}
+	case "short":
+		writeTemp = func(f *os.File, data []byte) (int, error) {
+			return f.Write(data[:len(data)/2])
+		}
+	case "sync":
+		syncFile = func(f *os.File) error { return syscall.EIO }
+	case "rename":
+		renameFn = func(from, to string) error { return syscall.EXDEV }
+	default:
+		t.Fatalf("unknown step %q", step)
+	}
+}
+
+func TestStore_SaveIsAtomic(t *testing.T) {
+	const old = "---\ntitle: Old\n---\nThe original content"
+	const updated = "---\ntitle: New\n---\n" + "A much longer replacement body. "
+
+	for _, step := range []string{"write", "short", "sync", "rename"} {
+		t.Run(step, func(t *testing.T) {
+			dir := t.TempDir()
+			store := NewStore(dir)
+			if err := store.Save("note", old); err != nil {
+				t.Fatal(err)
+			}
+
+			failAt(t, step)
+			err := store.Save("note", strings.Repeat(updated, 100))
+			if err == nil {
+				t.Fatal("expected Save to fail")
+			}
+			if step == "write" && !errors.Is(err, syscall.ENOSPC) {
+				t.Errorf("expected ENOSPC, got %v", err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(dir, "note.md"))
+			if err != nil || string(got) != old {
+				t.Errorf("old content lost: %q, %v", got, err)
+			}
+			entries, _ := os.ReadDir(dir)
+			for _, e := range entries {
+				if strings.Contains(e.Name(), ".tmp-") {
+					t.Errorf("temporary file %s left behind", e.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestStore_SavePreservesPermissions(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	path := filepath.Join(dir, "private.md")
+
+	if err := store.Save("private", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0644 {
+		t.Errorf("new note mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("private", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode after save = %v, want 0600", info.Mode().Perm())
+	}
+	if got, _ := os.ReadFile(path); string(got) != "v2" {
+		t.Errorf("content = %q, want v2", got)
+	}
+}
@@ -0,0 +1,166 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"marko-backend/internal/models"
+)
+
+// metaCache remembers the parsed list entry of every note, keyed by note ID
+// and validated against the file's mtime and size, so List only reads files
+// that changed since the last call. Entries are also dropped explicitly on
+// writes through the Store, which covers edits landing within the mtime
+// granularity, and refreshed by Get, which the watcher calls on external
+// changes.
+type metaCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	path    string // persistence file, empty for memory only
+	dirty   bool
+}
+
+type cacheEntry struct {
+	ModTime int64       `json:"mtime"`
+	Size    int64       `json:"size"`
+	Note    models.Note `json:"note"`
+}
+
+// cacheFormat is bumped whenever cacheEntry or the parser output changes,
+// so stale persisted caches are discarded.
+const cacheFormat = 4
+
+type cacheFile struct {
+	Format  int                   `json:"format"`
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+func newMetaCache() *metaCache {
+	return &metaCache{entries: make(map[string]cacheEntry)}
+}
+
+func (c *metaCache) get(id string, info fs.FileInfo) (models.Note, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[id]
+	if !ok || e.ModTime != info.ModTime().UnixNano() || e.Size != info.Size() {
+		return models.Note{}, false
+	}
+	return e.Note, true
+}
+
+func (c *metaCache) put(id string, info fs.FileInfo, note models.Note) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := cacheEntry{ModTime: info.ModTime().UnixNano(), Size: info.Size(), Note: ListEntry(note)}
+	if old, ok := c.entries[id]; ok && reflect.DeepEqual(old, e) {
+		return // Get of an unchanged note: nothing to persist
+	}
+	c.entries[id] = e
+	c.dirty = true
+}
+
+// ListEntry strips a parsed note down to what List returns. The body and
+// everything derived from it are only served by Get. Every NoteRepository
+// lists notes this way.
+func ListEntry(note models.Note) models.Note {
+	note.Content = ""
+	note.Version = "" // Only known from the full file, see Get
+	note.Links = nil
+	note.Outline = nil
+	note.Tasks = nil
+	note.Snippets = nil
+	return note
+}
+
+// invalidate drops id, or with prefix set every ID below that folder.
+func (c *metaCache) invalidate(id string, prefix bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if key == id || (prefix && strings.HasPrefix(key, id+"/")) {
+			delete(c.entries, key)
+			c.dirty = true
+		}
+	}
+}
+
+// retain drops every entry not in seen, i.e. notes deleted behind our back.
+func (c *metaCache) retain(seen map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if !seen[key] {
+			delete(c.entries, key)
+			c.dirty = true
+		}
+	}
+}
+
+func (c *metaCache) load(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Format != cacheFormat {
+		// A corrupt or outdated cache is just a cold one
+		return nil
+	}
+	for id, e := range f.Entries {
+		c.entries[id] = e
+	}
+	return nil
+}
+
+// flush writes the cache to its persistence file if it changed.
+func (c *metaCache) flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+	data, err := json.Marshal(cacheFile{Format: cacheFormat, Entries: c.entries})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	if err := writeFile(c.path, data, 0644); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// MetadataCachePath is the default persistence file for the metadata cache
+// of a vault. It lives in a hidden folder, which List never descends into.
+func MetadataCachePath(dir string) string {
+	return filepath.Join(dir, ".cache", "metadata.json")
+}
+
+// PersistMetadata loads the metadata cache from path and keeps it saved
+// there after every List that changed it, so restarts begin warm.
+func (s *Store) PersistMetadata(path string) error {
+	return s.cache.load(path)
+}
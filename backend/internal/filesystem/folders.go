@@ -0,0 +1,158 @@
+package filesystem
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"marko-backend/internal/models"
+)
+
+// Folders returns the folder tree of the vault, rooted at the store directory.
+func (s *Store) Folders() (models.Folder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.folderTree(s.Dir)
+}
+
+func (s *Store) folderTree(dir string) (models.Folder, error) {
+	folder := models.Folder{Notes: []string{}, Children: []models.Folder{}}
+	if dir != s.Dir {
+		folder.Path = s.relID(dir)
+		folder.Name = filepath.Base(dir)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return folder, err
+	}
+
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if e.IsDir() {
+			child, err := s.folderTree(path)
+			if err != nil {
+				continue
+			}
+			folder.Children = append(folder.Children, child)
+		} else if strings.HasSuffix(e.Name(), ".md") {
+			folder.Notes = append(folder.Notes, s.relID(path))
+		}
+	}
+	return folder, nil
+}
+
+// CreateFolder creates a folder (and any missing parents) inside the vault.
+func (s *Store) CreateFolder(folder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.resolve(folder)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(path, 0755)
+}
+
+// RenameFolder moves a folder and everything in it. IDs of the contained
+// notes change accordingly; callers are responsible for updating indexes.
+func (s *Store) RenameFolder(oldFolder, newFolder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldPath, err := s.resolve(oldFolder)
+	if err != nil {
+		return err
+	}
+	newPath, err := s.resolve(newFolder)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(oldPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a folder", oldFolder)
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("folder %q: %w", newFolder, ErrExists)
+	}
+	if rel, err := filepath.Rel(oldPath, newPath); err == nil && !strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("cannot move folder %q into itself", oldFolder)
+	}
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	s.cache.invalidate(s.relID(oldPath), true)
+	// History directories mirror the folder layout, so move them as a whole
+	return s.moveHistory(s.relID(oldPath), s.relID(newPath))
+}
+
+// DeleteFolder removes a folder. Unless recursive is set, the folder must
+// not contain any notes or subfolders; with recursive set, the folder and
+// its notes are moved to the trash.
+func (s *Store) DeleteFolder(folder string, recursive bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.resolve(folder)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a folder", folder)
+	}
+
+	if !recursive {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("folder %q: %w", folder, ErrNotEmpty)
+		}
+	}
+
+	s.cache.invalidate(s.relID(path), true)
+	if recursive {
+		return s.moveToTrash(path)
+	}
+	return os.Remove(path)
+}
+
+// NotesIn returns metadata for every note below the given folder.
+func (s *Store) NotesIn(folder string) ([]models.NoteInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	path, err := s.resolve(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []models.NoteInfo{}
+	err = s.walkNotes(path, func(id, _ string, info fs.FileInfo) {
+		infos = append(infos, models.NoteInfo{ID: id, Size: info.Size(), ModTime: info.ModTime()})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
@@ -0,0 +1,153 @@
+package filesystem
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"marko-backend/internal/models"
+)
+
+// historyDirName is the hidden directory holding per-note revisions.
+// Each note gets a subdirectory named after its ID, with one file per
+// revision named by its Unix nanosecond timestamp.
+const historyDirName = ".history"
+
+// Revisions lists the stored revisions of a note, newest first.
+func (s *Store) Revisions(id string) ([]models.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	path, err := s.notePath(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.revisions(s.relID(path))
+}
+
+// GetRevision returns the raw content of a single revision.
+func (s *Store) GetRevision(id, rev string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := strconv.ParseInt(rev, 10, 64); err != nil {
+		return "", fmt.Errorf("invalid revision %q: %w", rev, os.ErrNotExist)
+	}
+
+	path, err := s.notePath(id)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(filepath.Join(s.historyDir(s.relID(path)), rev+".md"))
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+func (s *Store) historyDir(noteID string) string {
+	return filepath.Join(s.Dir, historyDirName, filepath.FromSlash(noteID))
+}
+
+// revisions reads the history directory of a note. Callers must hold s.mu.
+func (s *Store) revisions(noteID string) ([]models.Revision, error) {
+	entries, err := os.ReadDir(s.historyDir(noteID))
+	if os.IsNotExist(err) {
+		return []models.Revision{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	revs := []models.Revision{}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".md")
+		nanos, err := strconv.ParseInt(name, 10, 64)
+		if e.IsDir() || err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		revs = append(revs, models.Revision{
+			ID:        name,
+			NoteID:    noteID,
+			CreatedAt: time.Unix(0, nanos),
+			Size:      info.Size(),
+		})
+	}
+
+	sort.Slice(revs, func(i, j int) bool { return revs[i].CreatedAt.After(revs[j].CreatedAt) })
+	return revs, nil
+}
+
+// recordRevision snapshots content into the note's history before it is
+// written to path. If the note predates its history, the current file is
+// snapshotted first so the pre-existing version is never lost.
+// Callers must hold s.mu for writing.
+func (s *Store) recordRevision(noteID, path string, content []byte) error {
+	revs, err := s.revisions(noteID)
+	if err != nil {
+		return err
+	}
+
+	var latest []byte
+	if len(revs) > 0 {
+		latest, err = os.ReadFile(filepath.Join(s.historyDir(noteID), revs[0].ID+".md"))
+		if err != nil {
+			return err
+		}
+	} else if existing, err := os.ReadFile(path); err == nil {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := s.writeRevision(noteID, existing, info.ModTime()); err != nil {
+			return err
+		}
+		latest = existing
+	}
+
+	if latest != nil && bytes.Equal(latest, content) {
+		return nil // Unchanged, nothing to record
+	}
+	return s.writeRevision(noteID, content, time.Now())
+}
+
+func (s *Store) writeRevision(noteID string, content []byte, at time.Time) error {
+	dir := s.historyDir(noteID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// Bump the timestamp on collision so revision IDs stay unique
+	nanos := at.UnixNano()
+	for {
+		path := filepath.Join(dir, strconv.FormatInt(nanos, 10)+".md")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return writeFile(path, content, 0644)
+		}
+		nanos++
+	}
+}
+
+// moveHistory carries a note's revisions over to its new ID after a rename.
+// Callers must hold s.mu for writing.
+func (s *Store) moveHistory(oldID, newID string) error {
+	oldDir := s.historyDir(oldID)
+	if _, err := os.Stat(oldDir); os.IsNotExist(err) {
+		return nil
+	}
+	newDir := s.historyDir(newID)
+	if err := os.MkdirAll(filepath.Dir(newDir), 0755); err != nil {
+		return err
+	}
+	return os.Rename(oldDir, newDir)
+}
@@ -3,34 +3,46 @@ package filesystem
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
 	"strings"
 	"time"
 
+	"marko-backend/internal/frontmatter"
+	"marko-backend/internal/links"
+	"marko-backend/internal/markdown"
 	"marko-backend/internal/models"
 )
 
 // ParseNoteContent parses the raw file content into metadata and body.
-// It implements a simple custom parser to avoid external dependencies.
+// Frontmatter is parsed by the dependency-free frontmatter package.
 func ParseNoteContent(id string, content []byte, fileModTime time.Time) models.Note {
 	meta := models.NoteMetadata{}
 	body := string(content)
+	bodyLine := 1 // line of the file the body starts on
 
 	// Check for frontmatter
-	if bytes.HasPrefix(content, []byte("---\n")) || bytes.HasPrefix(content, []byte("---\r\n")) {
-		parts := strings.SplitN(string(content), "---", 3)
-		if len(parts) == 3 {
-			// part 0 is empty (before first ---)
-			// part 1 is frontmatter
-			// part 2 is body
-			parseFrontmatter(parts[1], &meta)
-			body = strings.TrimSpace(parts[2])
+	if front, rest, ok := frontmatter.Split(body); ok {
+		if doc, err := frontmatter.Parse(front); err == nil {
+			applyFrontmatter(doc, &meta)
+		} else {
+			// Not valid YAML: salvage the well-known keys line by line
+			parseFrontmatter(front, &meta)
 		}
+		body = strings.TrimSpace(rest)
+		start := len(content) - len(strings.TrimLeft(rest, " \t\r\n"))
+		bodyLine += strings.Count(string(content[:start]), "\n")
 	}
 
 	// Fallback/Defaults
 	if meta.Title == "" {
-		// Use ID or filename as title if missing
-		meta.Title = strings.TrimSuffix(id, ".md")
+		// Use ID or filename as title if missing (ignoring any folder prefix)
+		meta.Title = strings.TrimSuffix(path.Base(id), ".md")
 		meta.Title = strings.ReplaceAll(meta.Title, "-", " ")
 		meta.Title = strings.Title(meta.Title)
 	}
@@ -55,9 +67,235 @@ func ParseNoteContent(id string, content []byte, fileModTime time.Time) models.N
 		Content:   body,
 		CreatedAt: created,
 		UpdatedAt: updated,
+		Tags:      meta.Tags,
+		Author:    meta.Author,
+		Version:   ContentVersion(content),
+		Metadata:  meta.Extra,
+		Links:     noteLinks(id, string(content)),
+		Outline:   outline(body, bodyLine),
+		Tasks:     noteTasks(body, bodyLine),
+		Snippets:  noteSnippets(id, body, bodyLine),
 	}
 }
 
+// noteSnippets lists the fenced code blocks of body, with line numbers
+// counted in the file where body starts at line bodyLine.
+func noteSnippets(id, body string, bodyLine int) []models.Snippet {
+	var out []models.Snippet
+	for _, b := range markdown.CodeBlocks(body) {
+		out = append(out, models.Snippet{
+			NoteID:  id,
+			Line:    b.Line + bodyLine - 1,
+			Lang:    b.Lang,
+			Code:    b.Code,
+			Heading: b.Heading,
+			Anchor:  b.Anchor,
+		})
+	}
+	return out
+}
+
+// outline lists the headings of body, with line numbers counted in the file
+// where body starts at line bodyLine.
+func outline(body string, bodyLine int) []models.Heading {
+	var out []models.Heading
+	for _, h := range markdown.Outline(body) {
+		out = append(out, models.Heading{
+			Level:  h.Level,
+			Text:   h.Text,
+			Anchor: h.Anchor,
+			Line:   h.Line + bodyLine - 1,
+		})
+	}
+	return out
+}
+
+// maxLinkContext caps the length of Link.Context in bytes.
+const maxLinkContext = 200
+
+// noteLinks lists the links in a note's raw content (frontmatter included,
+// so line numbers match the file).
+func noteLinks(id, content string) []models.Link {
+	if !strings.HasSuffix(id, ".md") {
+		id += ".md"
+	}
+	var out []models.Link
+	var lines []string
+	for _, l := range links.Scan(content) {
+		target, ok := links.Resolve(id, l)
+		if !ok {
+			continue
+		}
+		if lines == nil {
+			lines = strings.Split(content, "\n")
+		}
+		context := strings.TrimSpace(lines[l.Line-1])
+		if len(context) > maxLinkContext {
+			context = context[:maxLinkContext]
+		}
+		out = append(out, models.Link{
+			Target:  l.Target,
+			ID:      target,
+			Kind:    l.Kind.String(),
+			Anchor:  l.Anchor,
+			Text:    l.Text,
+			Line:    l.Line,
+			Context: strings.ToValidUTF8(context, ""),
+		})
+	}
+	return out
+}
+
+// applyFrontmatter copies the well-known keys of a parsed frontmatter block
+// into meta and keeps every other key in meta.Extra.
+func applyFrontmatter(doc *frontmatter.Document, meta *models.NoteMetadata) {
+	for _, key := range doc.Keys() {
+		value, _ := doc.Get(key)
+		switch key {
+		case "title":
+			meta.Title = scalarString(value)
+		case "created":
+			meta.Created = scalarString(value)
+		case "updated":
+			meta.Updated = scalarString(value)
+		case "tags":
+			meta.Tags = stringList(value)
+		case "author":
+			meta.Author = scalarString(value)
+		default:
+			if meta.Extra == nil {
+				meta.Extra = make(map[string]any)
+			}
+			meta.Extra[key] = value
+		}
+	}
+}
+
+func scalarString(v any) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// stringList accepts both YAML lists and comma-separated strings.
+// Tags written as "#go" are stored as "go".
+func stringList(v any) []string {
+	var out []string
+	add := func(s string) {
+		if s = strings.TrimPrefix(strings.TrimSpace(s), "#"); s != "" {
+			out = append(out, s)
+		}
+	}
+	switch t := v.(type) {
+	case []any:
+		for _, item := range t {
+			add(scalarString(item))
+		}
+	case nil:
+	default:
+		for _, s := range strings.Split(scalarString(t), ",") {
+			add(s)
+		}
+	}
+	return out
+}
+
+// MergeNoteContent builds the file content for an update of a note whose
+// current raw content is existing (nil for a new note).
+//
+// If incoming has a frontmatter block of its own, it is used as-is.
+// Otherwise incoming is treated as the new body and the existing
+// frontmatter is kept verbatim, including keys this server does not know.
+// Finally, the entries of set are written into the frontmatter, leaving all
+// other keys byte-for-byte unchanged.
+func MergeNoteContent(existing []byte, incoming string, set map[string]any) (string, error) {
+	front, body := "", incoming
+	if f, rest, ok := frontmatter.Split(incoming); ok {
+		front, body = f, rest
+	} else if f, rest, ok := frontmatter.Split(string(existing)); ok {
+		// Keep the original spacing between frontmatter and body
+		sep := rest[:len(rest)-len(strings.TrimLeft(rest, "\r\n"))]
+		front, body = f, sep+incoming
+	} else if len(set) > 0 {
+		body = "\n" + incoming
+	}
+
+	if len(set) > 0 {
+		doc, err := frontmatter.Parse(front)
+		if err != nil {
+			return "", err
+		}
+		keys := make([]string, 0, len(set))
+		for k := range set {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if set[k] == nil {
+				doc.Delete(k)
+			} else {
+				doc.Set(k, set[k])
+			}
+		}
+		front = doc.String()
+	}
+
+	return frontmatter.Join(front, body), nil
+}
+
+// maxHeaderSize bounds how much of a file readHeader will consume looking
+// for the end of the frontmatter block.
+const maxHeaderSize = 64 << 10
+
+// readHeader returns the leading frontmatter block of the file at path
+// (including both delimiter lines), or just the first line if the file has
+// no frontmatter. The rest of the file is not read.
+func readHeader(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var header []byte
+	for len(header) < maxHeaderSize {
+		line, err := r.ReadSlice('\n')
+		first := len(header) == 0
+		header = append(header, line...)
+		if first && !bytes.HasPrefix(line, []byte("---")) {
+			return header, nil
+		}
+		if err == io.EOF {
+			return header, nil
+		}
+		if err == bufio.ErrBufferFull {
+			continue // overlong line, keep reading it
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if t := strings.TrimRight(string(line), "\r\n \t"); !first && (t == "---" || t == "...") {
+			return header, nil
+		}
+	}
+	return header, nil
+}
+
+// ContentVersion returns the version tag of a note's raw file content.
+// It changes whenever a single byte of the file changes.
+func ContentVersion(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
+
+// parseFrontmatter is a lenient line-based key:value parser used when the
+// frontmatter is not valid YAML.
 func parseFrontmatter(raw string, meta *models.NoteMetadata) {
 	scanner := bufio.NewScanner(strings.NewReader(raw))
 	for scanner.Scan() {
@@ -82,11 +320,9 @@ func parseFrontmatter(raw string, meta *models.NoteMetadata) {
 				meta.Updated = val
 			case "tags":
 				// Very basic tag parsing [a, b]
-				val = strings.Trim(val, "[]")
-				tags := strings.Split(val, ",")
-				for _, t := range tags {
-					meta.Tags = append(meta.Tags, strings.TrimSpace(t))
-				}
+				meta.Tags = append(meta.Tags, stringList(strings.Trim(val, "[]"))...)
+			case "author":
+				meta.Author = val
 			}
 		}
 	}
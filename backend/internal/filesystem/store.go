@@ -1,8 +1,12 @@
 package filesystem
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -11,47 +15,48 @@ import (
 )
 
 type Store struct {
-	Dir string
-	mu  sync.RWMutex
+	Dir   string
+	mu    sync.RWMutex
+	cache *metaCache
 }
 
 func NewStore(dir string) *Store {
-	return &Store{Dir: dir}
+	return &Store{Dir: dir, cache: newMetaCache()}
 }
 
 func (s *Store) List() ([]models.Note, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	files, err := os.ReadDir(s.Dir)
-	if err != nil {
-		return nil, err
-	}
-
 	// Initialize as empty slice so it marshals to [] instead of null
 	notes := []models.Note{}
-	for _, f := range files {
-		if !f.IsDir() && strings.HasSuffix(f.Name(), ".md") {
-			info, err := f.Info()
-			if err != nil {
-				continue
-			}
-			
-			// We optimize list by not reading full content of every file if possible,
-			// but to get Title we might need to read the header.
-			// For simplicity and correctness with the requirement "If frontmatter is missing, derive title",
-			// we will read the file. Modern SSDs can handle this for reasonable note counts.
-			// For optimization we could limit reading to the first 500 bytes.
-			
-			content, err := os.ReadFile(filepath.Join(s.Dir, f.Name()))
-			if err != nil {
-				continue
-			}
-			
-			note := ParseNoteContent(f.Name(), content, info.ModTime())
-			note.Content = "" // Don't return full content in list
+	seen := make(map[string]bool)
+	err := s.walkNotes(s.Dir, func(id, path string, info fs.FileInfo) {
+		seen[id] = true
+		if note, ok := s.cache.get(id, info); ok {
 			notes = append(notes, note)
+			return
+		}
+
+		// Titles, dates and tags all live in the frontmatter, so only the
+		// header is read. Without frontmatter the title comes from the
+		// filename and the body is never needed.
+		header, err := readHeader(path)
+		if err != nil {
+			return
 		}
+
+		note := ListEntry(ParseNoteContent(id, header, info.ModTime()))
+		s.cache.put(id, info, note)
+		notes = append(notes, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.retain(seen)
+	if err := s.cache.flush(); err != nil {
+		log.Printf("Saving metadata cache: %v", err)
 	}
 	return notes, nil
 }
@@ -60,17 +65,16 @@ func (s *Store) Get(id string) (models.Note, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	path := filepath.Join(s.Dir, id)
-	
-	// If file doesn't exist, try appending .md
-	// This helps when ID in URL is "note-123" but file is "note-123.md"
-	if _, err := os.Stat(path); os.IsNotExist(err) && !strings.HasSuffix(id, ".md") {
-		path = filepath.Join(s.Dir, id+".md")
+	path, err := s.notePath(id)
+	if err != nil {
+		return models.Note{}, err
 	}
 
-	// Security check to prevent directory traversal
-	if !strings.HasPrefix(filepath.Clean(path), filepath.Clean(s.Dir)) {
-		return models.Note{}, fmt.Errorf("invalid path")
+	// Stat first: if the file changes in between, the cached entry is
+	// older than the file and List reparses it.
+	info, err := os.Stat(path)
+	if err != nil {
+		return models.Note{}, err
 	}
 
 	content, err := os.ReadFile(path)
@@ -78,18 +82,48 @@ func (s *Store) Get(id string) (models.Note, error) {
 		return models.Note{}, err
 	}
 
-	info, err := os.Stat(path)
-	if err != nil {
-		return models.Note{}, err
+	note := ParseNoteContent(id, content, info.ModTime())
+	if !info.IsDir() {
+		cached := note
+		cached.ID = s.relID(path)
+		s.cache.put(cached.ID, info, cached)
 	}
+	return note, nil
+}
 
-	return ParseNoteContent(id, content, info.ModTime()), nil
+// Raw returns the unparsed file content of a note, frontmatter included.
+func (s *Store) Raw(id string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	path, err := s.notePath(id)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
 }
 
 func (s *Store) Save(id string, content string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	return s.save(id, content)
+}
+
+// SaveIfMatch is like Save, but fails with ErrVersionConflict unless the
+// stored note's Version equals version.
+func (s *Store) SaveIfMatch(id, content, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkVersion(id, version); err != nil {
+		return err
+	}
+	return s.save(id, content)
+}
+
+// save writes a note. Callers must hold s.mu for writing.
+func (s *Store) save(id string, content string) error {
 	// Ensure directory exists
 	if err := os.MkdirAll(s.Dir, 0755); err != nil {
 		return err
@@ -103,15 +137,248 @@ func (s *Store) Save(id string, content string) error {
 	if !strings.HasSuffix(id, ".md") {
 		id = id + ".md"
 	}
-	
-	path := filepath.Join(s.Dir, id)
-	return os.WriteFile(path, []byte(content), 0644)
+
+	path, err := s.resolve(id)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := s.recordRevision(s.relID(path), path, []byte(content)); err != nil {
+		return fmt.Errorf("record revision: %w", err)
+	}
+	s.cache.invalidate(s.relID(path), false)
+	return writeFile(path, []byte(content), 0644)
 }
 
 func (s *Store) Delete(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	path := filepath.Join(s.Dir, id)
-	return os.Remove(path)
+	return s.delete(id)
+}
+
+// DeleteIfMatch is like Delete, but fails with ErrVersionConflict unless the
+// stored note's Version equals version.
+func (s *Store) DeleteIfMatch(id, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkVersion(id, version); err != nil {
+		return err
+	}
+	return s.delete(id)
+}
+
+// delete moves a note to the trash. Callers must hold s.mu for writing.
+func (s *Store) delete(id string) error {
+	path, err := s.notePath(id)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	s.cache.invalidate(s.relID(path), false)
+	return s.moveToTrash(path)
+}
+
+// checkVersion compares the stored note's Version with version.
+// Callers must hold s.mu.
+func (s *Store) checkVersion(id, version string) error {
+	path, err := s.notePath(id)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if ContentVersion(content) != version {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// Rename moves the note stored under oldID to newID.
+// It refuses to overwrite an existing note.
+func (s *Store) Rename(oldID, newID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if newID == "" {
+		return fmt.Errorf("id required")
+	}
+
+	oldPath, err := s.notePath(oldID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(oldPath); err != nil {
+		return err
+	}
+
+	if !strings.HasSuffix(newID, ".md") {
+		newID = newID + ".md"
+	}
+	newPath, err := s.notePath(newID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("note %q: %w", newID, ErrExists)
+	}
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	s.cache.invalidate(s.relID(oldPath), false)
+	return s.moveHistory(s.relID(oldPath), s.relID(newPath))
+}
+
+// Stat returns file-level metadata for a note without reading its content.
+func (s *Store) Stat(id string) (models.NoteInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	path, err := s.notePath(id)
+	if err != nil {
+		return models.NoteInfo{}, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return models.NoteInfo{}, err
+	}
+
+	return models.NoteInfo{ID: s.relID(path), Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// ListInfo returns file-level metadata for every note without reading content.
+func (s *Store) ListInfo() ([]models.NoteInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := []models.NoteInfo{}
+	err := s.walkNotes(s.Dir, func(id, path string, info fs.FileInfo) {
+		infos = append(infos, models.NoteInfo{ID: id, Size: info.Size(), ModTime: info.ModTime()})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// walkNotes calls fn for every .md file below root, in lexical order.
+// IDs are slash-separated paths relative to the store directory. Hidden
+// files and directories (e.g. .history) are skipped.
+// Callers must hold s.mu.
+func (s *Store) walkNotes(root string, fn func(id, path string, info fs.FileInfo)) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil // Skip unreadable entries
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".md") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fn(s.relID(path), path, info)
+		return nil
+	})
+}
+
+// notePath resolves a note ID to a file path inside the store directory.
+// Callers must hold s.mu.
+func (s *Store) notePath(id string) (string, error) {
+	path, err := s.resolve(id)
+	if err != nil {
+		return "", err
+	}
+
+	// If file doesn't exist, try appending .md
+	// This helps when ID in URL is "note-123" but file is "note-123.md"
+	// A folder with the same name as the note does not count as a match.
+	if fi, err := os.Stat(path); (os.IsNotExist(err) || (err == nil && fi.IsDir())) && !strings.HasSuffix(id, ".md") {
+		path += ".md"
+	}
+
+	return path, nil
+}
+
+// resolve maps a slash-separated ID (note or folder) to a path inside the
+// store directory. It rejects absolute paths, ".." and hidden segments so a
+// request can never reach outside the vault or into its internal directories.
+func (s *Store) resolve(id string) (string, error) {
+	rel, err := CleanID(id)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(s.Dir, filepath.FromSlash(rel))
+
+	// Security check to prevent directory traversal
+	if r, err := filepath.Rel(s.Dir, path); err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidPath
+	}
+
+	return path, nil
+}
+
+// relID converts a path inside the store directory back to a slash-separated ID.
+func (s *Store) relID(path string) string {
+	rel, err := filepath.Rel(s.Dir, path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+var (
+	// ErrInvalidPath is returned for IDs that would escape the store directory.
+	ErrInvalidPath = errors.New("invalid path")
+
+	// ErrVersionConflict is returned by conditional writes when the note
+	// changed since the caller read it.
+	ErrVersionConflict = errors.New("note was modified concurrently")
+
+	// ErrExists is returned when a note or folder would overwrite another.
+	ErrExists = errors.New("already exists")
+
+	// ErrNotEmpty is returned when deleting a folder that still has
+	// content without asking for a recursive delete.
+	ErrNotEmpty = errors.New("not empty")
+)
+
+// CleanID validates a slash-separated note or folder ID and returns it in
+// canonical form (e.g. "go//concurrency/" becomes "go/concurrency").
+func CleanID(id string) (string, error) {
+	if id == "" || strings.Contains(id, "\\") || strings.ContainsRune(id, 0) || path.IsAbs(id) {
+		return "", ErrInvalidPath
+	}
+	for _, seg := range strings.Split(id, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return "", ErrInvalidPath
+		}
+	}
+	clean := path.Clean(id)
+	if clean == "." {
+		return "", ErrInvalidPath
+	}
+	return clean, nil
 }
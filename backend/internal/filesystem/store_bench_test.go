@@ -0,0 +1,73 @@
+package filesystem
+
+import (
+	"fmt"
+	"testing"
+)
+
+const benchNotes = 2000
+
+func newBenchStore(b *testing.B) *Store {
+	b.Helper()
+	store := NewStore(b.TempDir())
+	for i := 0; i < benchNotes; i++ {
+		content := fmt.Sprintf("---\ntitle: Note %d\ntags: [bench, n%d]\ncreated: 2024-01-02\n---\n", i, i%10)
+		for j := 0; j < 50; j++ {
+			content += "Lorem ipsum dolor sit amet, consectetur adipiscing elit.\n"
+		}
+		if err := store.Save(fmt.Sprintf("folder-%d/note-%d", i%20, i), content); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return store
+}
+
+// BenchmarkStore_ListCold lists through a fresh Store every time, so every
+// file is opened and parsed (the behaviour without the metadata cache).
+func BenchmarkStore_ListCold(b *testing.B) {
+	dir := newBenchStore(b).Dir
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewStore(dir).List(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkStore_ListCached lists through one Store, so only the directory
+// walk and stat calls remain after the first iteration.
+func BenchmarkStore_ListCached(b *testing.B) {
+	store := newBenchStore(b)
+	if _, err := store.List(); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := store.List(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkStore_ListPersisted starts each iteration from a fresh Store
+// that loads the persisted cache, as after a server restart.
+func BenchmarkStore_ListPersisted(b *testing.B) {
+	store := newBenchStore(b)
+	cachePath := MetadataCachePath(store.Dir)
+	if err := store.PersistMetadata(cachePath); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := store.List(); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := NewStore(store.Dir)
+		if err := s.PersistMetadata(cachePath); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := s.List(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
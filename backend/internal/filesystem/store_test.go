@@ -1,9 +1,14 @@
 package filesystem
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"marko-backend/internal/models"
 )
 
 func TestStore_SaveAndGet(t *testing.T) {
@@ -56,3 +61,529 @@ func TestParseNoteContent(t *testing.T) {
 		t.Errorf("Expected Body content, got %s", note.Content)
 	}
 }
+
+func TestStore_NestedFolders(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	if err := store.Save("go/concurrency", "# Channels"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := store.Save("top", "# Top"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	note, err := store.Get("go/concurrency")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if note.Title != "Concurrency" {
+		t.Errorf("Expected title 'Concurrency', got '%s'", note.Title)
+	}
+
+	notes, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(notes) != 2 || notes[0].ID != "go/concurrency.md" || notes[1].ID != "top.md" {
+		t.Errorf("unexpected list: %+v", notes)
+	}
+
+	if err := store.RenameFolder("go", "lang/go"); err != nil {
+		t.Fatalf("RenameFolder failed: %v", err)
+	}
+	tree, err := store.Folders()
+	if err != nil {
+		t.Fatalf("Folders failed: %v", err)
+	}
+	if len(tree.Children) != 1 || tree.Children[0].Path != "lang" ||
+		len(tree.Children[0].Children) != 1 || tree.Children[0].Children[0].Notes[0] != "lang/go/concurrency.md" {
+		t.Errorf("unexpected tree: %+v", tree)
+	}
+
+	if err := store.DeleteFolder("lang", false); !errors.Is(err, ErrNotEmpty) {
+		t.Errorf("DeleteFolder of a non-empty folder = %v, want ErrNotEmpty", err)
+	}
+	if err := store.DeleteFolder("lang", true); err != nil {
+		t.Fatalf("DeleteFolder failed: %v", err)
+	}
+}
+
+func TestStore_RejectsTraversal(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	for _, id := range []string{"../escape", "a/../../escape", "/etc/passwd", ".history/x", ""} {
+		if err := store.Save(id, "x"); err == nil {
+			t.Errorf("Save(%q) should fail", id)
+		}
+		if _, err := store.Get(id); err == nil {
+			t.Errorf("Get(%q) should fail", id)
+		}
+		if err := store.Delete(id); err == nil {
+			t.Errorf("Delete(%q) should fail", id)
+		}
+	}
+}
+
+func TestStore_Revisions(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	// A note written before history existed is captured on first save
+	if err := os.WriteFile(dir+"/note.md", []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"v2", "v2", "v3"} {
+		if err := store.Save("note", content); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	revs, err := store.Revisions("note")
+	if err != nil {
+		t.Fatalf("Revisions failed: %v", err)
+	}
+	if len(revs) != 3 {
+		t.Fatalf("Expected 3 revisions, got %d", len(revs))
+	}
+
+	oldest, err := store.GetRevision("note", revs[2].ID)
+	if err != nil {
+		t.Fatalf("GetRevision failed: %v", err)
+	}
+	if oldest != "v1" {
+		t.Errorf("Expected oldest revision 'v1', got '%s'", oldest)
+	}
+
+	if err := store.Rename("note", "moved"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if revs, _ := store.Revisions("moved"); len(revs) != 3 {
+		t.Errorf("Expected history to follow rename, got %d revisions", len(revs))
+	}
+
+	// History is hidden from listings
+	if notes, _ := store.List(); len(notes) != 1 {
+		t.Errorf("Expected 1 note, got %d", len(notes))
+	}
+}
+
+func TestParseNoteContent_Metadata(t *testing.T) {
+	raw := []byte("---\ntitle: \"Go: Channels\"\nauthor: Alice\ntags:\n  - go\n  - concurrency\nreview:\n  score: 4\n---\n\nBody")
+	note := ParseNoteContent("go/channels.md", raw, time.Now())
+
+	if note.Title != "Go: Channels" {
+		t.Errorf("Expected 'Go: Channels', got '%s'", note.Title)
+	}
+	if note.Author != "Alice" {
+		t.Errorf("Expected author 'Alice', got '%s'", note.Author)
+	}
+	if len(note.Tags) != 2 || note.Tags[0] != "go" || note.Tags[1] != "concurrency" {
+		t.Errorf("Expected tags [go concurrency], got %v", note.Tags)
+	}
+	if review, ok := note.Metadata["review"].(map[string]any); !ok || review["score"] != 4 {
+		t.Errorf("Expected nested review map, got %#v", note.Metadata["review"])
+	}
+	for _, key := range []string{"title", "author", "tags"} {
+		if _, ok := note.Metadata[key]; ok {
+			t.Errorf("Known key %q must not be duplicated into metadata", key)
+		}
+	}
+}
+
+func TestParseNoteContent_LenientTags(t *testing.T) {
+	// Not valid YAML, so the line-based fallback parses it
+	note := ParseNoteContent("a.md", []byte("---\ntitle: [x\ntags: #go, db\n---\nbody"), time.Now())
+	if strings.Join(note.Tags, ",") != "go,db" {
+		t.Errorf("tags = %q, want [go db]", note.Tags)
+	}
+}
+
+func TestMergeNoteContent(t *testing.T) {
+	existing := []byte("---\ntitle: Old\nauthor: Alice # original author\ncustom: [1, 2]\n---\n\nOld body")
+
+	// A body-only update keeps the frontmatter verbatim
+	got, err := MergeNoteContent(existing, "New body", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "---\ntitle: Old\nauthor: Alice # original author\ncustom: [1, 2]\n---\n\nNew body"
+	if got != want {
+		t.Errorf("unexpected merge:\n%s", got)
+	}
+
+	// Setting keys only rewrites those keys
+	got, err = MergeNoteContent(existing, "New body", map[string]any{"title": "New", "custom": nil, "rating": 5.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "---\ntitle: New\nauthor: Alice # original author\nrating: 5\n---\n\nNew body"
+	if got != want {
+		t.Errorf("unexpected merge:\n%s", got)
+	}
+
+	// Content with its own frontmatter replaces the stored one
+	got, _ = MergeNoteContent(existing, "---\ntitle: Mine\n---\nBody", nil)
+	if got != "---\ntitle: Mine\n---\nBody" {
+		t.Errorf("unexpected merge:\n%s", got)
+	}
+}
+
+func TestStore_ListMatchesGet(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	files := map[string]string{
+		"plain":      "# Heading\n\nNo frontmatter here",
+		"meta":       "---\ntitle: With Meta\ntags: [go, db]\nauthor: Alice\ncreated: 2024-01-02\n---\n" + strings.Repeat("body line\n", 10000),
+		"dots":       "---\ntitle: Dots\n...\nBody",
+		"unfinished": "---\ntitle: Never Closed\nBody without a closing line",
+		"rule":       "----\nNot frontmatter",
+	}
+	for id, content := range files {
+		if err := store.Save(id, content); err != nil {
+			t.Fatalf("Save %s: %v", id, err)
+		}
+	}
+
+	notes, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(notes) != len(files) {
+		t.Fatalf("expected %d notes, got %d", len(files), len(notes))
+	}
+	for _, n := range notes {
+		full, err := store.Get(n.ID)
+		if err != nil {
+			t.Fatalf("Get %s: %v", n.ID, err)
+		}
+		if n.Title != full.Title || n.Author != full.Author || !n.CreatedAt.Equal(full.CreatedAt) ||
+			strings.Join(n.Tags, ",") != strings.Join(full.Tags, ",") {
+			t.Errorf("%s: List gave %+v, Get gave title=%q author=%q tags=%v created=%v",
+				n.ID, n, full.Title, full.Author, full.Tags, full.CreatedAt)
+		}
+		if n.Content != "" || n.Version != "" {
+			t.Errorf("%s: List should omit content and version", n.ID)
+		}
+	}
+}
+
+func TestStore_MetadataCache(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	cachePath := MetadataCachePath(dir)
+	if err := store.PersistMetadata(cachePath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save("a", "---\ntitle: One\n---\nBody"); err != nil {
+		t.Fatal(err)
+	}
+	titleOf := func(s *Store) string {
+		t.Helper()
+		notes, err := s.List()
+		if err != nil || len(notes) != 1 {
+			t.Fatalf("List = %v, %v", notes, err)
+		}
+		return notes[0].Title
+	}
+	if got := titleOf(store); got != "One" {
+		t.Fatalf("title = %q", got)
+	}
+
+	// Same size, same mtime: only the explicit invalidation in Save
+	// can catch this one.
+	path := filepath.Join(dir, "a.md")
+	info, _ := os.Stat(path)
+	if err := store.Save("a", "---\ntitle: Two\n---\nBody"); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+	if got := titleOf(store); got != "Two" {
+		t.Errorf("after Save, title = %q, want Two", got)
+	}
+
+	// External edit: mtime and size change
+	if err := os.WriteFile(path, []byte("---\ntitle: Three!\n---\nBody"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, info.ModTime().Add(time.Second), info.ModTime().Add(time.Second))
+	if got := titleOf(store); got != "Three!" {
+		t.Errorf("after external edit, title = %q, want Three!", got)
+	}
+
+	// A fresh store picks the cache up from disk instead of parsing
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Fatalf("cache not persisted: %v", err)
+	}
+	restarted := NewStore(dir)
+	if err := restarted.PersistMetadata(cachePath); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := restarted.cache.get("a.md", mustStat(t, path)); !ok {
+		t.Error("persisted cache entry not loaded")
+	}
+	if got := titleOf(restarted); got != "Three!" {
+		t.Errorf("restarted title = %q", got)
+	}
+
+	if err := store.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if notes, _ := store.List(); len(notes) != 0 {
+		t.Errorf("deleted note still listed: %v", notes)
+	}
+}
+
+func TestStore_ListAfterGet(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	cachePath := MetadataCachePath(dir)
+	if err := store.PersistMetadata(cachePath); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("a", "# Title\n\n- [ ] task\n\n```go\ncode()\n```\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading an unchanged note leaves the persisted cache alone
+	if _, err := store.List(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	if store.cache.dirty {
+		t.Error("Get of an unchanged note marked the cache dirty")
+	}
+
+	// Get caches the note it parsed; List must still get a list entry
+	store.cache.invalidate("a.md", false)
+	if _, err := store.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := store.List()
+	if err != nil || len(notes) != 1 {
+		t.Fatalf("List = %v, %v", notes, err)
+	}
+	if n := notes[0]; n.Content != "" || n.Outline != nil || n.Tasks != nil || n.Snippets != nil {
+		t.Errorf("List returned parsed body fields: %+v", n)
+	}
+
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"outline"`) || strings.Contains(string(data), `"tasks"`) ||
+		strings.Contains(string(data), "code()") {
+		t.Errorf("persisted cache holds parsed body fields: %s", data)
+	}
+}
+
+func mustStat(t *testing.T, path string) os.FileInfo {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestStore_Trash(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	if err := store.Save("go/channels", "---\ntitle: Channels\n---\nBody"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("go/channels"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if notes, _ := store.List(); len(notes) != 0 {
+		t.Errorf("trashed note still listed: %v", notes)
+	}
+
+	items, err := store.Trash()
+	if err != nil || len(items) != 1 {
+		t.Fatalf("Trash = %v, %v", items, err)
+	}
+	item := items[0]
+	if item.NoteID != "go/channels.md" || item.Title != "Channels" || time.Since(item.DeletedAt) > time.Minute {
+		t.Errorf("unexpected trash item %+v", item)
+	}
+
+	// A new note took the old place: restoring must not overwrite it
+	if err := store.Save("go/channels", "replacement"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.RestoreTrash(item.ID); !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists, got %v", err)
+	}
+	if err := store.Delete("go/channels"); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := store.RestoreTrash(item.ID)
+	if err != nil || id != "go/channels.md" {
+		t.Fatalf("RestoreTrash = %q, %v", id, err)
+	}
+	if note, err := store.Get(id); err != nil || note.Title != "Channels" {
+		t.Errorf("restored note = %+v, %v", note, err)
+	}
+	if _, err := store.RestoreTrash(item.ID); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("restoring twice: expected ErrNotExist, got %v", err)
+	}
+	if _, err := store.RestoreTrash("../../etc/passwd"); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("expected ErrInvalidPath, got %v", err)
+	}
+
+	// Recursive folder deletes land in the trash too
+	if err := store.DeleteFolder("go", true); err != nil {
+		t.Fatal(err)
+	}
+	items, _ = store.Trash()
+	if len(items) != 2 {
+		t.Fatalf("expected 2 trash items, got %v", items)
+	}
+
+	if n, err := store.EmptyTrash(time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("EmptyTrash(an hour ago) = %d, %v; want nothing purged", n, err)
+	}
+	if err := store.PurgeTrash(items[0].ID); err != nil {
+		t.Fatalf("PurgeTrash failed: %v", err)
+	}
+	if n, err := store.EmptyTrash(time.Time{}); err != nil || n != 1 {
+		t.Errorf("EmptyTrash = %d, %v; want 1", n, err)
+	}
+	if slots, _ := os.ReadDir(filepath.Join(dir, ".trash")); len(slots) != 0 {
+		t.Errorf("empty trash slots left behind: %v", slots)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".history", "go", "channels.md")); !os.IsNotExist(err) {
+		t.Errorf("history of purged note kept: %v", err)
+	}
+}
+
+func TestStore_TrashAttachmentsAndHistory(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	// The same note deleted twice: two trash items share its history
+	for _, body := range []string{"one", "two"} {
+		if err := store.Save("docs/a", body); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Delete("docs/a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, err := store.Trash()
+	if err != nil || len(items) != 2 {
+		t.Fatalf("Trash = %v, %v", items, err)
+	}
+	history := filepath.Join(dir, ".history", "docs", "a.md")
+	if err := store.PurgeTrash(items[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(history); err != nil {
+		t.Errorf("history dropped while another trash item holds the note: %v", err)
+	}
+
+	// A folder with an attachment and a hidden subfolder
+	if err := store.Save("docs/b", "b"); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "docs", "image.png"), []byte("png"), 0644)
+	os.MkdirAll(filepath.Join(dir, "docs", ".assets"), 0755)
+	os.WriteFile(filepath.Join(dir, "docs", ".assets", "x.bin"), []byte("x"), 0644)
+	if err := store.DeleteFolder("docs", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := store.EmptyTrash(time.Time{}); err != nil || n != 2 {
+		t.Errorf("EmptyTrash = %d, %v; want 2", n, err)
+	}
+	if slots, _ := os.ReadDir(filepath.Join(dir, ".trash")); len(slots) != 0 {
+		t.Errorf("trash slots left behind: %v", slots)
+	}
+	if _, err := os.Stat(history); !os.IsNotExist(err) {
+		t.Errorf("history of purged note kept: %v", err)
+	}
+}
+
+func TestParseNoteContent_Links(t *testing.T) {
+	raw := []byte("---\ntitle: Index\nrelated: \"[[go/basics]]\"\n---\n# Index\n\nRead [[Channels|chans]] and [spec](ref/spec%20v2.md#types).\n")
+	note := ParseNoteContent("notes/index.md", raw, time.Now())
+
+	if len(note.Links) != 3 {
+		t.Fatalf("expected 3 links, got %+v", note.Links)
+	}
+	want := []struct{ id, kind string }{
+		{"go/basics.md", "wiki"},
+		{"Channels.md", "wiki"},
+		{"notes/ref/spec v2.md", "markdown"},
+	}
+	for i, w := range want {
+		if note.Links[i].ID != w.id || note.Links[i].Kind != w.kind {
+			t.Errorf("link %d = %+v, want %s (%s)", i, note.Links[i], w.id, w.kind)
+		}
+	}
+	if l := note.Links[1]; l.Line != 7 || l.Text != "chans" || l.Context != "Read [[Channels|chans]] and [spec](ref/spec%20v2.md#types)." {
+		t.Errorf("unexpected link details %+v", l)
+	}
+}
+
+func TestParseNoteContent_Outline(t *testing.T) {
+	raw := []byte("---\ntitle: Guide\n---\n\n# Guide\n\n## Install *it*\n\ntext\n\n## Install it\n")
+	note := ParseNoteContent("guide.md", raw, time.Now())
+
+	want := []models.Heading{
+		{Level: 1, Text: "Guide", Anchor: "guide", Line: 5},
+		{Level: 2, Text: "Install it", Anchor: "install-it", Line: 7},
+		{Level: 2, Text: "Install it", Anchor: "install-it-1", Line: 11},
+	}
+	if len(note.Outline) != len(want) {
+		t.Fatalf("Outline = %+v, want %+v", note.Outline, want)
+	}
+	for i, w := range want {
+		if note.Outline[i] != w {
+			t.Errorf("heading %d = %+v, want %+v", i, note.Outline[i], w)
+		}
+	}
+}
+
+func TestParseNoteContent_Tasks(t *testing.T) {
+	raw := "---\ntitle: Plan\n---\n- [ ] book flights @2026-11-01 !high #travel\n- [x] pack #Travel #travel\n\n```\n- [ ] not a task\n```\n> - [ ] quoted @2026-13-40\n"
+	note := ParseNoteContent("plan.md", []byte(raw), time.Now())
+
+	if len(note.Tasks) != 3 {
+		t.Fatalf("expected 3 tasks, got %+v", note.Tasks)
+	}
+	first := note.Tasks[0]
+	if first.Line != 4 || first.Done || first.Due != "2026-11-01" || first.Priority != "high" ||
+		len(first.Tags) != 1 || first.Tags[0] != "travel" {
+		t.Errorf("unexpected task %+v", first)
+	}
+	if second := note.Tasks[1]; !second.Done || len(second.Tags) != 1 {
+		t.Errorf("unexpected task %+v", second)
+	}
+	if third := note.Tasks[2]; third.Line != 10 || third.Due != "" {
+		t.Errorf("unexpected task %+v", third)
+	}
+
+	// Toggling rewrites only the checkbox on that line
+	got, task, err := ToggleTask("plan.md", []byte(raw), 10, nil)
+	if err != nil || !task.Done {
+		t.Fatalf("ToggleTask = %+v, %v", task, err)
+	}
+	if want := strings.Replace(raw, "> - [ ] quoted", "> - [x] quoted", 1); got != want {
+		t.Errorf("ToggleTask rewrote\n%s", got)
+	}
+	done := false
+	if got, _, _ := ToggleTask("plan.md", []byte(raw), 5, &done); got != strings.Replace(raw, "- [x] pack", "- [ ] pack", 1) {
+		t.Errorf("ToggleTask rewrote\n%s", got)
+	}
+	for _, line := range []int{1, 8, 99} {
+		if _, _, err := ToggleTask("plan.md", []byte(raw), line, nil); !errors.Is(err, ErrNoTask) {
+			t.Errorf("line %d: expected ErrNoTask, got %v", line, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package filesystem
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"marko-backend/internal/markdown"
+	"marko-backend/internal/models"
+)
+
+// ErrNoTask is returned when a line holds no task list item.
+var ErrNoTask = errors.New("no task on this line")
+
+var (
+	dueRe      = regexp.MustCompile(`(?:^|\s)@(\d{4}-\d{2}-\d{2})\b`)
+	priorityRe = regexp.MustCompile(`(?i)(?:^|\s)!(high|medium|low)\b`)
+	hashtagRe  = regexp.MustCompile(`(?:^|\s)#([\p{L}_][\p{L}\p{N}_/-]*)`)
+
+	// checkboxRe matches a task item's line up to its checkbox, inside any
+	// blockquote markers
+	checkboxRe = regexp.MustCompile(`^((?:[ \t]*>)*[ \t]*(?:[-+*]|\d{1,9}[.)])[ \t]+\[)([ xX])\]`)
+)
+
+// noteTasks lists the task list items of body, with line numbers counted
+// in the file where body starts at line bodyLine.
+func noteTasks(body string, bodyLine int) []models.Task {
+	var out []models.Task
+	for _, t := range markdown.Tasks(body) {
+		task := models.Task{
+			Line: t.Line + bodyLine - 1,
+			Text: t.Text,
+			Done: t.Done,
+		}
+		if m := dueRe.FindStringSubmatch(t.Text); m != nil {
+			if _, err := time.Parse("2006-01-02", m[1]); err == nil {
+				task.Due = m[1]
+			}
+		}
+		if m := priorityRe.FindStringSubmatch(t.Text); m != nil {
+			task.Priority = strings.ToLower(m[1])
+		}
+		seen := make(map[string]bool)
+		for _, m := range hashtagRe.FindAllStringSubmatch(t.Text, -1) {
+			if key := strings.ToLower(m[1]); !seen[key] {
+				seen[key] = true
+				task.Tags = append(task.Tags, m[1])
+			}
+		}
+		out = append(out, task)
+	}
+	return out
+}
+
+// ToggleTask checks or unchecks the task list item on the given line of a
+// note's raw content, or flips it when done is nil. Only the checkbox on
+// that line changes. It returns the new content and the updated task, or
+// ErrNoTask if the line holds no task (including lines inside code
+// blocks that merely look like one).
+func ToggleTask(id string, content []byte, line int, done *bool) (string, models.Task, error) {
+	note := ParseNoteContent(id, content, time.Time{})
+	var task *models.Task
+	for i := range note.Tasks {
+		if note.Tasks[i].Line == line {
+			task = &note.Tasks[i]
+			break
+		}
+	}
+	lines := strings.Split(string(content), "\n")
+	if task == nil || line > len(lines) {
+		return "", models.Task{}, ErrNoTask
+	}
+	m := checkboxRe.FindStringSubmatchIndex(lines[line-1])
+	if m == nil {
+		return "", models.Task{}, ErrNoTask
+	}
+
+	task.Done = !task.Done
+	if done != nil {
+		task.Done = *done
+	}
+	if l := lines[line-1]; (l[m[4]] != ' ') != task.Done {
+		mark := " "
+		if task.Done {
+			mark = "x"
+		}
+		lines[line-1] = l[:m[4]] + mark + l[m[5]:]
+	}
+	return strings.Join(lines, "\n"), *task, nil
+}
@@ -0,0 +1,275 @@
+package filesystem
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"marko-backend/internal/models"
+)
+
+// Deleted notes are moved to .trash/<unixnano>/<noteID>, where the first
+// segment is the deletion time. A recursively deleted folder lands in one
+// such directory as a whole. Trash item IDs are the path below .trash, e.g.
+// "1718000000000000000/go/channels.md". Like .history, the trash is a
+// hidden folder, so its notes never show up in List, the watcher or search.
+const trashDir = ".trash"
+
+// moveToTrash moves the note or folder at path into a new trash slot.
+// Callers must hold s.mu for writing.
+func (s *Store) moveToTrash(path string) error {
+	// Bump the timestamp on collision so every deletion gets its own slot
+	for nanos := time.Now().UnixNano(); ; nanos++ {
+		slot := filepath.Join(s.Dir, trashDir, strconv.FormatInt(nanos, 10))
+		if _, err := os.Stat(slot); err == nil {
+			continue
+		}
+		dest := filepath.Join(slot, filepath.FromSlash(s.relID(path)))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		return os.Rename(path, dest)
+	}
+}
+
+// Trash lists the deleted notes, most recently deleted first.
+func (s *Store) Trash() ([]models.TrashItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.trashItems()
+}
+
+func (s *Store) trashItems() ([]models.TrashItem, error) {
+	root := filepath.Join(s.Dir, trashDir)
+	slots, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return []models.TrashItem{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items := []models.TrashItem{}
+	for _, slot := range slots {
+		nanos, err := strconv.ParseInt(slot.Name(), 10, 64)
+		if !slot.IsDir() || err != nil {
+			continue
+		}
+		slotDir := filepath.Join(root, slot.Name())
+		err = filepath.WalkDir(slotDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(d.Name(), ".md") {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(slotDir, path)
+			noteID := filepath.ToSlash(rel)
+
+			item := models.TrashItem{
+				ID:        slot.Name() + "/" + noteID,
+				NoteID:    noteID,
+				DeletedAt: time.Unix(0, nanos),
+				Size:      info.Size(),
+			}
+			if header, err := readHeader(path); err == nil {
+				item.Title = ParseNoteContent(noteID, header, info.ModTime()).Title
+			}
+			items = append(items, item)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].DeletedAt.Equal(items[j].DeletedAt) {
+			return items[i].DeletedAt.After(items[j].DeletedAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
+
+// trashPath resolves a trash item ID to its file.
+func (s *Store) trashPath(id string) (string, error) {
+	slot, noteID, ok := strings.Cut(id, "/")
+	if !ok {
+		return "", ErrInvalidPath
+	}
+	if _, err := strconv.ParseInt(slot, 10, 64); err != nil {
+		return "", ErrInvalidPath
+	}
+	noteID, err := CleanID(noteID)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.Dir, trashDir, slot, filepath.FromSlash(noteID))
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("trash item %q: %w", id, fs.ErrNotExist)
+	}
+	return path, nil
+}
+
+// RestoreTrash moves a trashed note back to where it was deleted from and
+// returns its ID. It fails with ErrExists if a note took its place since.
+func (s *Store) RestoreTrash(id string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, err := s.trashPath(id)
+	if err != nil {
+		return "", err
+	}
+	_, noteID, _ := strings.Cut(id, "/")
+	noteID, _ = CleanID(noteID)
+
+	to, err := s.resolve(noteID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(to); err == nil {
+		return "", fmt.Errorf("note %q: %w", noteID, ErrExists)
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(from, to); err != nil {
+		return "", err
+	}
+	s.cache.invalidate(noteID, false)
+	s.pruneTrash(filepath.Dir(from))
+	return noteID, nil
+}
+
+// PurgeTrash permanently deletes a trashed note, along with its revision
+// history unless a note with the same ID exists again or is still in the
+// trash.
+func (s *Store) PurgeTrash(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.trashPath(id)
+	if err != nil {
+		return err
+	}
+	return s.purge(id, path)
+}
+
+// EmptyTrash permanently deletes every note trashed before cutoff, or all
+// of them if cutoff is zero, and returns how many were removed. Whole
+// slots are removed, so attachments and hidden folders deleted along with
+// a folder go too.
+func (s *Store) EmptyTrash(cutoff time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	root := filepath.Join(s.Dir, trashDir)
+	slots, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var purged []string
+	for _, slot := range slots {
+		nanos, err := strconv.ParseInt(slot.Name(), 10, 64)
+		if !slot.IsDir() || err != nil {
+			continue
+		}
+		if !cutoff.IsZero() && !time.Unix(0, nanos).Before(cutoff) {
+			continue
+		}
+		dir := filepath.Join(root, slot.Name())
+		noteIDs := slotNotes(dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return len(purged), err
+		}
+		purged = append(purged, noteIDs...)
+	}
+	// Only now that every slot is gone can history be checked for
+	// other trash items holding the same note
+	for _, noteID := range purged {
+		if err := s.dropHistory(noteID); err != nil {
+			return len(purged), err
+		}
+	}
+	return len(purged), nil
+}
+
+// purge removes one trash item, and its slot with whatever else is left
+// in it once no note is. Callers must hold s.mu for writing.
+func (s *Store) purge(id, path string) error {
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	slot, noteID, _ := strings.Cut(id, "/")
+	slotDir := filepath.Join(s.Dir, trashDir, slot)
+	if len(slotNotes(slotDir)) == 0 {
+		if err := os.RemoveAll(slotDir); err != nil {
+			return err
+		}
+	} else {
+		s.pruneTrash(filepath.Dir(path))
+	}
+	return s.dropHistory(noteID)
+}
+
+// slotNotes returns the IDs of the notes in a trash slot.
+func slotNotes(slotDir string) []string {
+	var ids []string
+	filepath.WalkDir(slotDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(d.Name(), ".md") {
+			rel, _ := filepath.Rel(slotDir, path)
+			ids = append(ids, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return ids
+}
+
+// dropHistory removes the revision history of noteID unless a note with
+// that ID exists again or a trash item still holds one. Callers must hold
+// s.mu for writing.
+func (s *Store) dropHistory(noteID string) error {
+	live, err := s.resolve(noteID)
+	if err != nil {
+		return nil
+	}
+	if _, err := os.Stat(live); !os.IsNotExist(err) {
+		return nil
+	}
+	root := filepath.Join(s.Dir, trashDir)
+	slots, _ := os.ReadDir(root)
+	for _, slot := range slots {
+		if _, err := os.Stat(filepath.Join(root, slot.Name(), filepath.FromSlash(noteID))); err == nil {
+			return nil
+		}
+	}
+	return os.RemoveAll(s.historyDir(noteID))
+}
+
+// pruneTrash removes dir and its parents up to .trash while they are empty.
+func (s *Store) pruneTrash(dir string) {
+	root := filepath.Join(s.Dir, trashDir)
+	for dir != root && strings.HasPrefix(dir, root) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,320 @@
+// Package frontmatter reads and writes the YAML frontmatter block at the top
+// of a note. Documents remember the original text of every top-level entry,
+// so rewriting one key leaves all others (including comments and unknown
+// keys) byte-for-byte unchanged.
+package frontmatter
+
+import (
+	"sort"
+	"strings"
+)
+
+// Split separates a "---" delimited frontmatter block from the body.
+// The block must start on the first line and end with a "---" or "..." line.
+// ok is false if there is no (complete) frontmatter block.
+func Split(content string) (front, body string, ok bool) {
+	rest, found := strings.CutPrefix(content, "---\n")
+	if !found {
+		rest, found = strings.CutPrefix(content, "---\r\n")
+	}
+	if !found {
+		return "", content, false
+	}
+
+	offset := 0
+	for offset <= len(rest) {
+		end := strings.IndexByte(rest[offset:], '\n')
+		var l string
+		if end < 0 {
+			l = rest[offset:]
+		} else {
+			l = rest[offset : offset+end]
+		}
+		if t := strings.TrimRight(l, "\r \t"); t == "---" || t == "..." {
+			body := ""
+			if end >= 0 {
+				body = rest[offset+end+1:]
+			}
+			return rest[:offset], body, true
+		}
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+	return "", content, false
+}
+
+// Join assembles a note from a frontmatter block and a body. An empty
+// frontmatter block is omitted.
+func Join(front, body string) string {
+	if strings.TrimSpace(front) == "" {
+		return body
+	}
+	if !strings.HasSuffix(front, "\n") {
+		front += "\n"
+	}
+	return "---\n" + front + "---\n" + body
+}
+
+type entry struct {
+	key     string
+	value   any
+	raw     string // Original text, "" once the entry has been modified
+	trailer string // Comments and blank lines following the entry
+}
+
+// Document is a parsed frontmatter block with ordered top-level keys.
+type Document struct {
+	preamble string // Comments and blank lines before the first key
+	entries  []entry
+}
+
+// Parse parses the text between the frontmatter fences.
+func Parse(src string) (*Document, error) {
+	p := newParser(src)
+	doc := &Document{}
+
+	var starts []int
+	err := p.parseMap(0, func(key string, value any, start int) {
+		doc.entries = append(doc.entries, entry{key: key, value: value})
+		starts = append(starts, start)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if l, ok := p.peek(); ok {
+		return nil, p.errorf(l, "unexpected content")
+	}
+
+	// Slice the source into per-entry chunks for lossless round-tripping.
+	// Each chunk runs up to the next top-level key (trailing comments and
+	// blank lines included).
+	raw := p.raw
+	if len(raw) > 0 && raw[len(raw)-1] == "" {
+		raw = raw[:len(raw)-1] // Drop the empty string after a final newline
+	}
+	if len(starts) == 0 {
+		doc.preamble = joinLines(raw)
+		return doc, nil
+	}
+	doc.preamble = joinLines(raw[:starts[0]])
+	for i := range doc.entries {
+		end := len(raw)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		// Comments after the value survive when the entry is rewritten
+		valueEnd := end
+		for valueEnd > starts[i]+1 && isTrivia(raw[valueEnd-1]) {
+			valueEnd--
+		}
+		doc.entries[i].raw = joinLines(raw[starts[i]:valueEnd])
+		doc.entries[i].trailer = joinLines(raw[valueEnd:end])
+	}
+	return doc, nil
+}
+
+// isTrivia reports whether a line is blank or a top-level comment.
+func isTrivia(l string) bool {
+	t := strings.TrimSpace(l)
+	return t == "" || strings.HasPrefix(l, "#")
+}
+
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Keys returns the top-level keys in document order.
+func (d *Document) Keys() []string {
+	keys := make([]string, len(d.entries))
+	for i, e := range d.entries {
+		keys[i] = e.key
+	}
+	return keys
+}
+
+// Get returns the value of a top-level key.
+func (d *Document) Get(key string) (any, bool) {
+	for _, e := range d.entries {
+		if e.key == key {
+			return e.value, true
+		}
+	}
+	return nil, false
+}
+
+// Set adds or replaces a top-level key. New keys are appended.
+func (d *Document) Set(key string, value any) {
+	for i, e := range d.entries {
+		if e.key == key {
+			d.entries[i] = entry{key: key, value: value, trailer: e.trailer}
+			return
+		}
+	}
+	d.entries = append(d.entries, entry{key: key, value: value})
+}
+
+// Delete removes a top-level key.
+func (d *Document) Delete(key string) {
+	for i, e := range d.entries {
+		if e.key == key {
+			d.entries = append(d.entries[:i], d.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// Map returns all top-level entries as a map.
+func (d *Document) Map() map[string]any {
+	m := make(map[string]any, len(d.entries))
+	for _, e := range d.entries {
+		m[e.key] = e.value
+	}
+	return m
+}
+
+// String renders the document. Unmodified entries are reproduced exactly
+// as they were parsed.
+func (d *Document) String() string {
+	var sb strings.Builder
+	sb.WriteString(d.preamble)
+	for _, e := range d.entries {
+		if e.raw != "" {
+			sb.WriteString(e.raw)
+		} else {
+			encodeEntry(&sb, e.key, e.value, 0)
+		}
+		sb.WriteString(e.trailer)
+	}
+	return sb.String()
+}
+
+// encodeEntry writes "key: value" at the given indentation. Scalar lists
+// use the compact flow style ([a, b]) common in frontmatter.
+func encodeEntry(sb *strings.Builder, key string, value any, indent int) {
+	pad := strings.Repeat(" ", indent)
+	sb.WriteString(pad + encodeScalar(key) + ":")
+
+	switch v := normalize(value).(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			sb.WriteString(" {}\n")
+			return
+		}
+		sb.WriteString("\n")
+		for _, k := range sortedKeys(v) {
+			encodeEntry(sb, k, v[k], indent+2)
+		}
+	case []any:
+		if flow, ok := encodeFlowSeq(v); ok {
+			sb.WriteString(" " + flow + "\n")
+			return
+		}
+		sb.WriteString("\n")
+		encodeSeq(sb, v, indent+2)
+	case string:
+		if strings.Contains(v, "\n") {
+			encodeLiteral(sb, v, indent+2)
+			return
+		}
+		sb.WriteString(" " + encodeScalar(v) + "\n")
+	default:
+		sb.WriteString(" " + encodeScalar(v) + "\n")
+	}
+}
+
+func encodeSeq(sb *strings.Builder, items []any, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for _, item := range items {
+		switch v := normalize(item).(type) {
+		case map[string]any:
+			if len(v) == 0 {
+				sb.WriteString(pad + "- {}\n")
+				continue
+			}
+			// First key shares the dash line, the rest align with it
+			var inner strings.Builder
+			for _, k := range sortedKeys(v) {
+				encodeEntry(&inner, k, v[k], indent+2)
+			}
+			sb.WriteString(pad + "- " + strings.TrimPrefix(inner.String(), pad+"  "))
+		case []any:
+			if flow, ok := encodeFlowSeq(v); ok {
+				sb.WriteString(pad + "- " + flow + "\n")
+				continue
+			}
+			sb.WriteString(pad + "-\n")
+			encodeSeq(sb, v, indent+2)
+		case string:
+			if strings.Contains(v, "\n") {
+				sb.WriteString(pad + "-")
+				encodeLiteral(sb, v, indent+2)
+				continue
+			}
+			sb.WriteString(pad + "- " + encodeScalar(v) + "\n")
+		default:
+			sb.WriteString(pad + "- " + encodeScalar(v) + "\n")
+		}
+	}
+}
+
+// encodeFlowSeq renders a list of single-line scalars as [a, b].
+func encodeFlowSeq(items []any) (string, bool) {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		switch v := normalize(item).(type) {
+		case map[string]any, []any:
+			return "", false
+		case string:
+			if strings.Contains(v, "\n") {
+				return "", false
+			}
+			if needsQuotes(v) || strings.ContainsAny(v, ",[]{}") {
+				parts[i] = quote(v)
+				continue
+			}
+			parts[i] = v
+		default:
+			parts[i] = encodeScalar(v)
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]", true
+}
+
+// encodeLiteral writes a multi-line string as a literal block scalar,
+// choosing the chomping indicator that reproduces trailing newlines.
+func encodeLiteral(sb *strings.Builder, s string, indent int) {
+	trimmed := strings.TrimRight(s, "\n")
+	switch {
+	case len(s)-len(trimmed) == 0:
+		sb.WriteString(" |-\n")
+	case len(s)-len(trimmed) == 1:
+		sb.WriteString(" |\n")
+	default:
+		sb.WriteString(" |+\n")
+	}
+	pad := strings.Repeat(" ", indent)
+	for _, l := range strings.Split(trimmed, "\n") {
+		if l == "" {
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString(pad + l + "\n")
+	}
+	for i := 1; i < len(s)-len(trimmed); i++ {
+		sb.WriteString("\n")
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package frontmatter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// normalize converts common Go types produced by callers (rather than by
+// the parser) into the generic forms the encoder understands.
+func normalize(v any) any {
+	switch t := v.(type) {
+	case []string:
+		items := make([]any, len(t))
+		for i, s := range t {
+			items[i] = s
+		}
+		return items
+	case map[string]string:
+		m := make(map[string]any, len(t))
+		for k, s := range t {
+			m[k] = s
+		}
+		return m
+	}
+	return v
+}
+
+// encodeScalar renders a single-line scalar, quoting strings that would
+// otherwise be read back as something else.
+func encodeScalar(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(t)
+	case int:
+		return strconv.Itoa(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		switch {
+		case math.IsInf(t, 1):
+			return ".inf"
+		case math.IsInf(t, -1):
+			return "-.inf"
+		}
+		// Integral values (e.g. numbers decoded from JSON) are written as ints
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case string:
+		if needsQuotes(t) {
+			return quote(t)
+		}
+		return t
+	}
+	return quote(fmt.Sprint(v))
+}
+
+// needsQuotes reports whether a string cannot be written as a plain scalar.
+func needsQuotes(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) {
+		return true
+	}
+	if _, isString := resolvePlain(s).(string); !isString {
+		return true // "true", "42", "null", ...
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	return strings.Contains(s, ": ") || strings.Contains(s, " #") ||
+		strings.HasSuffix(s, ":") || strings.ContainsAny(s, "\t\r\n")
+}
+
+// quote renders a double-quoted scalar.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&sb, `\x%02x`, r)
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,154 @@
+package frontmatter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	front, body, ok := Split("---\ntitle: a---b\n---\n\n# Body\n---\nmore")
+	if !ok || front != "title: a---b\n" || body != "\n# Body\n---\nmore" {
+		t.Errorf("unexpected split: %q %q %v", front, body, ok)
+	}
+
+	if _, _, ok := Split("# No frontmatter\n---\n"); ok {
+		t.Error("frontmatter must start on the first line")
+	}
+	if _, _, ok := Split("---\ntitle: unterminated\n"); ok {
+		t.Error("unterminated frontmatter must not split")
+	}
+}
+
+func TestParse(t *testing.T) {
+	src := `# leading comment
+title: "Go: Concurrency \"Patterns\""
+author: Alice # inline comment
+tags: [go, 'concurrency', "a, b"]
+draft: false
+rating: 4.5
+count: 3
+created: 2026-01-30
+aliases:
+  - Channels
+  - key: value
+    other: 2
+nested:
+  deep:
+    list:
+    - x
+    - y
+  flow: {a: 1, b: [c, d]}
+empty:
+summary: |
+  Line one
+  Line two
+folded: >-
+  folded
+  text
+
+  new paragraph
+plain: this continues
+  on the next line
+`
+	doc, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := map[string]any{
+		"title":   `Go: Concurrency "Patterns"`,
+		"author":  "Alice",
+		"tags":    []any{"go", "concurrency", "a, b"},
+		"draft":   false,
+		"rating":  4.5,
+		"count":   3,
+		"created": "2026-01-30",
+		"aliases": []any{"Channels", map[string]any{"key": "value", "other": 2}},
+		"nested": map[string]any{
+			"deep": map[string]any{"list": []any{"x", "y"}},
+			"flow": map[string]any{"a": 1, "b": []any{"c", "d"}},
+		},
+		"empty":   nil,
+		"summary": "Line one\nLine two\n",
+		"folded":  "folded text\nnew paragraph",
+		"plain":   "this continues on the next line",
+	}
+	got := doc.Map()
+	for k, v := range want {
+		if !reflect.DeepEqual(got[k], v) {
+			t.Errorf("%s: got %#v, want %#v", k, got[k], v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d keys, want %d", len(got), len(want))
+	}
+
+	// Untouched documents round-trip exactly
+	if doc.String() != src {
+		t.Errorf("round trip changed the document:\n%s", doc.String())
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, src := range []string{
+		"title: \"unterminated\n",
+		"tags: [a, b\n",
+		"key: value\n  bad: indent\n",
+		"just a line\n",
+	} {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
+
+func TestDocument_SetPreservesOtherKeys(t *testing.T) {
+	src := "title: Old\n# about the author\nauthor:   Bob   # keep my spacing\ncustom:\n  nested: [1, 2]\n"
+	doc, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc.Set("title", "New: improved")
+	doc.Set("tags", []string{"go", "true"})
+	doc.Set("notes", "multi\nline\n")
+
+	want := "title: \"New: improved\"\n# about the author\nauthor:   Bob   # keep my spacing\ncustom:\n  nested: [1, 2]\n" +
+		"tags: [go, \"true\"]\nnotes: |\n  multi\n  line\n"
+	if got := doc.String(); got != want {
+		t.Errorf("unexpected document:\n%s", got)
+	}
+
+	// Encoded values parse back to the same data
+	again, err := Parse(doc.String())
+	if err != nil {
+		t.Fatalf("re-parse failed: %v", err)
+	}
+	if !reflect.DeepEqual(again.Map(), map[string]any{
+		"title":  "New: improved",
+		"author": "Bob",
+		"custom": map[string]any{"nested": []any{1, 2}},
+		"tags":   []any{"go", "true"},
+		"notes":  "multi\nline\n",
+	}) {
+		t.Errorf("unexpected re-parse: %#v", again.Map())
+	}
+}
+
+func TestEncode_RoundTripsNestedValues(t *testing.T) {
+	doc := &Document{}
+	value := map[string]any{
+		"list":  []any{map[string]any{"a": 1, "b": "two"}, []any{"x", map[string]any{"y": true}}},
+		"quote": "it's \"quoted\"",
+		"empty": map[string]any{},
+	}
+	doc.Set("data", value)
+
+	parsed, err := Parse(doc.String())
+	if err != nil {
+		t.Fatalf("Parse failed: %v\n%s", err, doc.String())
+	}
+	if got, _ := parsed.Get("data"); !reflect.DeepEqual(got, value) {
+		t.Errorf("round trip mismatch:\n%s\n%#v", doc.String(), got)
+	}
+}
@@ -0,0 +1,585 @@
+package frontmatter
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// This file implements the subset of YAML used in note frontmatter: block
+// mappings and sequences, flow collections ([a, b] and {a: b}), plain,
+// single- and double-quoted scalars, literal (|) and folded (>) block
+// scalars and comments. Anchors, aliases, tags and multi-document streams
+// are not supported.
+//
+// Mappings decode to map[string]any, sequences to []any and scalars to
+// string, bool, int, float64 or nil.
+
+// SyntaxError reports malformed frontmatter with a 1-based line number.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("frontmatter line %d: %s", e.Line, e.Msg)
+}
+
+type line struct {
+	num    int // 1-based, for errors
+	indent int
+	text   string // Without indentation
+}
+
+type parser struct {
+	raw   []string
+	lines []line
+	pos   int
+}
+
+func newParser(src string) *parser {
+	p := &parser{raw: strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")}
+	for i, r := range p.raw {
+		trimmed := strings.TrimLeft(r, " ")
+		p.lines = append(p.lines, line{num: i + 1, indent: len(r) - len(trimmed), text: strings.TrimRight(trimmed, " \t")})
+	}
+	return p
+}
+
+func (p *parser) errorf(l line, format string, args ...any) error {
+	return &SyntaxError{Line: l.num, Msg: fmt.Sprintf(format, args...)}
+}
+
+// skipBlank advances past empty and comment-only lines.
+func (p *parser) skipBlank() {
+	for p.pos < len(p.lines) {
+		t := p.lines[p.pos].text
+		if t != "" && !strings.HasPrefix(t, "#") {
+			return
+		}
+		p.pos++
+	}
+}
+
+// peek returns the next significant line.
+func (p *parser) peek() (line, bool) {
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return line{}, false
+	}
+	return p.lines[p.pos], true
+}
+
+// parseNode parses the block node starting at the next significant line,
+// provided it is indented at least minIndent.
+func (p *parser) parseNode(minIndent int) (any, error) {
+	l, ok := p.peek()
+	if !ok || l.indent < minIndent {
+		return nil, nil
+	}
+	if isSeqItem(l.text) {
+		return p.parseSeq(l.indent)
+	}
+	if _, _, ok := splitKey(l.text); ok {
+		m := map[string]any{}
+		err := p.parseMap(l.indent, func(key string, value any, _ int) { m[key] = value })
+		return m, err
+	}
+
+	// A lone scalar, possibly spanning several lines
+	p.pos++
+	return p.parseInline(l, l.indent-1)
+}
+
+// parseMap parses a block mapping at the given indentation, calling add for
+// each entry with the index of the line the entry starts on.
+func (p *parser) parseMap(indent int, add func(key string, value any, start int)) error {
+	for {
+		l, ok := p.peek()
+		if !ok || l.indent < indent {
+			return nil
+		}
+		if l.indent > indent {
+			return p.errorf(l, "unexpected indentation")
+		}
+
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return p.errorf(l, "expected \"key: value\"")
+		}
+		start := p.pos
+		p.pos++
+
+		var value any
+		var err error
+		switch {
+		case rest == "":
+			// Nested block; sequences may sit at the key's own indentation
+			if next, ok := p.peek(); ok && next.indent == indent && isSeqItem(next.text) {
+				value, err = p.parseSeq(indent)
+			} else {
+				value, err = p.parseNode(indent + 1)
+			}
+		default:
+			value, err = p.parseInline(line{num: l.num, indent: l.indent, text: rest}, indent)
+		}
+		if err != nil {
+			return err
+		}
+		add(key, value, start)
+	}
+}
+
+// parseSeq parses a block sequence whose dashes sit at the given indentation.
+func (p *parser) parseSeq(indent int) ([]any, error) {
+	items := []any{}
+	for {
+		l, ok := p.peek()
+		if !ok || l.indent != indent || !isSeqItem(l.text) {
+			if ok && l.indent > indent {
+				return nil, p.errorf(l, "unexpected indentation")
+			}
+			return items, nil
+		}
+
+		content := strings.TrimLeft(l.text[1:], " ")
+		if content == "" || strings.HasPrefix(content, "#") {
+			p.pos++
+			item, err := p.parseNode(indent + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+
+		// Re-read the item content as a node indented to its own column,
+		// so "- key: value" starts a nested mapping
+		col := indent + (len(l.text) - len(content))
+		p.lines[p.pos] = line{num: l.num, indent: col, text: content}
+		item, err := p.parseNode(col)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+// parseInline parses a value that starts on a key or item line. parent is
+// the indentation of the owning key: continuation lines of plain scalars
+// and the body of block scalars must be indented further.
+func (p *parser) parseInline(l line, parent int) (any, error) {
+	text := l.text
+
+	switch {
+	case strings.HasPrefix(text, "|") || strings.HasPrefix(text, ">"):
+		return p.parseBlockScalar(l, parent)
+	case strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{"):
+		// Flow collections may span lines until their brackets balance
+		for !flowBalanced(text) && p.pos < len(p.lines) {
+			text += " " + strings.TrimSpace(p.raw[p.pos])
+			p.pos++
+		}
+		f := &flowParser{s: text}
+		v, err := f.parse()
+		if err != nil {
+			return nil, p.errorf(l, "%v", err)
+		}
+		if rest := stripComment(strings.TrimSpace(f.s[f.i:])); rest != "" {
+			return nil, p.errorf(l, "unexpected %q after flow collection", rest)
+		}
+		return v, nil
+	case strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'"):
+		s, n, err := unquote(text)
+		if err != nil {
+			return nil, p.errorf(l, "%v", err)
+		}
+		if rest := stripComment(strings.TrimSpace(text[n:])); rest != "" {
+			return nil, p.errorf(l, "unexpected %q after quoted string", rest)
+		}
+		return s, nil
+	}
+
+	// Plain scalar, folded with any more-indented continuation lines
+	plain := stripComment(text)
+	for {
+		next, ok := p.peek()
+		if !ok || next.indent <= parent {
+			break
+		}
+		if _, _, isKey := splitKey(next.text); isKey || isSeqItem(next.text) {
+			break
+		}
+		plain += " " + stripComment(next.text)
+		p.pos++
+	}
+	return resolvePlain(plain), nil
+}
+
+// parseBlockScalar handles literal (|) and folded (>) scalars with optional
+// chomping (+/-) and indentation indicators.
+func (p *parser) parseBlockScalar(l line, parent int) (any, error) {
+	header := stripComment(l.text)
+	style, indicators := header[0], header[1:]
+
+	chomp := byte(0)
+	explicit := 0
+	for _, c := range indicators {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			explicit = int(c - '0')
+		default:
+			return nil, p.errorf(l, "invalid block scalar header %q", header)
+		}
+	}
+
+	// Collect raw lines more indented than the parent (blank lines included)
+	var body []string
+	indent := -1
+	if explicit > 0 {
+		indent = max(parent, 0) + explicit
+	}
+	for p.pos < len(p.raw) {
+		r := p.raw[p.pos]
+		trimmed := strings.TrimLeft(r, " ")
+		if strings.TrimSpace(r) == "" {
+			body = append(body, "")
+			p.pos++
+			continue
+		}
+		n := len(r) - len(trimmed)
+		if n <= parent {
+			break
+		}
+		if indent < 0 {
+			indent = n
+		}
+		if n < indent {
+			break
+		}
+		body = append(body, r[indent:])
+		p.pos++
+	}
+
+	// Trailing blank lines only matter for "keep" chomping
+	trailing := 0
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+		trailing++
+	}
+
+	var sb strings.Builder
+	if style == '|' {
+		sb.WriteString(strings.Join(body, "\n"))
+	} else {
+		// Folded: single newlines become spaces, blank lines and
+		// more-indented lines keep their line breaks
+		for i, b := range body {
+			if i > 0 {
+				prev := body[i-1]
+				switch {
+				case b == "":
+					sb.WriteString("\n")
+				case prev == "":
+					// The blank line already supplied the break
+				case strings.HasPrefix(b, " ") || strings.HasPrefix(prev, " "):
+					sb.WriteString("\n")
+				default:
+					sb.WriteString(" ")
+				}
+			}
+			sb.WriteString(b)
+		}
+	}
+
+	s := sb.String()
+	switch chomp {
+	case '-':
+	case '+':
+		if len(body) > 0 {
+			s += "\n"
+		}
+		s += strings.Repeat("\n", trailing)
+	default:
+		if len(body) > 0 {
+			s += "\n"
+		}
+	}
+	return s, nil
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitKey splits "key: rest" outside of quotes. Values may themselves
+// contain ": " (e.g. "title: Go: The Good Parts"); only the first
+// separator counts.
+func splitKey(text string) (key, rest string, ok bool) {
+	if isSeqItem(text) || strings.HasPrefix(text, "#") {
+		return "", "", false
+	}
+
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		k, n, err := unquote(text)
+		if err != nil || !strings.HasPrefix(text[n:], ":") {
+			return "", "", false
+		}
+		after := text[n+1:]
+		if after != "" && after[0] != ' ' && after[0] != '\t' {
+			return "", "", false
+		}
+		return k, strings.TrimSpace(after), true
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] != ':' {
+			continue
+		}
+		if i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t' {
+			key = strings.TrimSpace(text[:i])
+			if key == "" || strings.ContainsAny(key[:1], "[{") {
+				return "", "", false
+			}
+			return key, strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// stripComment removes a trailing " # comment" from a plain scalar.
+func stripComment(s string) string {
+	if strings.HasPrefix(s, "#") {
+		return ""
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, "\t#"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// unquote decodes a single- or double-quoted scalar at the start of s and
+// returns it along with the number of bytes consumed.
+func unquote(s string) (string, int, error) {
+	q := s[0]
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if q == '\'' {
+			if c == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					sb.WriteByte('\'')
+					i++
+					continue
+				}
+				return sb.String(), i + 1, nil
+			}
+			sb.WriteByte(c)
+			continue
+		}
+
+		switch c {
+		case '"':
+			return sb.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(s) {
+				return "", 0, fmt.Errorf("unterminated escape")
+			}
+			i++
+			switch e := s[i]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case '0':
+				sb.WriteByte(0)
+			case '"', '\\', '/', ' ':
+				sb.WriteByte(e)
+			case 'u', 'U', 'x':
+				size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+				if i+size >= len(s) {
+					return "", 0, fmt.Errorf("short \\%c escape", e)
+				}
+				r, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid \\%c escape", e)
+				}
+				sb.WriteRune(rune(r))
+				i += size
+			default:
+				return "", 0, fmt.Errorf("unknown escape \\%c", e)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted string")
+}
+
+var (
+	intPattern   = regexp.MustCompile(`^[-+]?(0|[1-9][0-9_]*)$`)
+	floatPattern = regexp.MustCompile(`^[-+]?([0-9][0-9_]*)?\.[0-9]+([eE][-+]?[0-9]+)?$|^[-+]?[0-9]+[eE][-+]?[0-9]+$`)
+)
+
+// resolvePlain applies the YAML core schema to a plain scalar. Dates are
+// intentionally left as strings.
+func resolvePlain(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	}
+	if intPattern.MatchString(s) {
+		if n, err := strconv.Atoi(strings.ReplaceAll(s, "_", "")); err == nil {
+			return n
+		}
+	}
+	if floatPattern.MatchString(s) {
+		if f, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// flowBalanced reports whether every bracket opened outside quotes is closed.
+func flowBalanced(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// flowParser parses flow collections such as [a, "b", {c: 1}].
+type flowParser struct {
+	s string
+	i int
+}
+
+func (f *flowParser) skipSpace() {
+	for f.i < len(f.s) && (f.s[f.i] == ' ' || f.s[f.i] == '\t') {
+		f.i++
+	}
+}
+
+func (f *flowParser) parse() (any, error) {
+	f.skipSpace()
+	if f.i >= len(f.s) {
+		return nil, fmt.Errorf("unexpected end of flow collection")
+	}
+	switch f.s[f.i] {
+	case '[':
+		return f.parseSeq()
+	case '{':
+		return f.parseMap()
+	case '"', '\'':
+		s, n, err := unquote(f.s[f.i:])
+		if err != nil {
+			return nil, err
+		}
+		f.i += n
+		return s, nil
+	}
+
+	// Plain scalar up to the next indicator
+	start := f.i
+	for f.i < len(f.s) && !strings.ContainsRune(",]}", rune(f.s[f.i])) {
+		if f.s[f.i] == ':' && (f.i+1 == len(f.s) || f.s[f.i+1] == ' ') {
+			break
+		}
+		f.i++
+	}
+	return resolvePlain(strings.TrimSpace(f.s[start:f.i])), nil
+}
+
+func (f *flowParser) parseSeq() (any, error) {
+	f.i++ // [
+	items := []any{}
+	for {
+		f.skipSpace()
+		if f.i >= len(f.s) {
+			return nil, fmt.Errorf("unterminated flow sequence")
+		}
+		if f.s[f.i] == ']' {
+			f.i++
+			return items, nil
+		}
+		v, err := f.parse()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] == ',' {
+			f.i++
+		} else if f.i < len(f.s) && f.s[f.i] != ']' {
+			return nil, fmt.Errorf("expected ',' or ']' in flow sequence")
+		}
+	}
+}
+
+func (f *flowParser) parseMap() (any, error) {
+	f.i++ // {
+	m := map[string]any{}
+	for {
+		f.skipSpace()
+		if f.i >= len(f.s) {
+			return nil, fmt.Errorf("unterminated flow mapping")
+		}
+		if f.s[f.i] == '}' {
+			f.i++
+			return m, nil
+		}
+		k, err := f.parse()
+		if err != nil {
+			return nil, err
+		}
+		f.skipSpace()
+		var v any
+		if f.i < len(f.s) && f.s[f.i] == ':' {
+			f.i++
+			if v, err = f.parse(); err != nil {
+				return nil, err
+			}
+		}
+		m[fmt.Sprint(k)] = v
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] == ',' {
+			f.i++
+		} else if f.i < len(f.s) && f.s[f.i] != '}' {
+			return nil, fmt.Errorf("expected ',' or '}' in flow mapping")
+		}
+	}
+}
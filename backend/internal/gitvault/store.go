@@ -0,0 +1,381 @@
+// Package gitvault implements a note store that versions the vault directory
+// as a local git repository, committing on every change.
+package gitvault
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"marko-backend/internal/filesystem"
+	"marko-backend/internal/models"
+)
+
+// DefaultMessage is the commit message template used when none is configured.
+const DefaultMessage = "{{.Action}} {{.ID}}"
+
+// Config controls the identity and messages of the commits made by Store.
+type Config struct {
+	AuthorName  string
+	AuthorEmail string
+
+	// MessageTemplate is a text/template rendered with a MessageData.
+	MessageTemplate string
+}
+
+// MessageData is passed to the commit message template.
+type MessageData struct {
+	Action string // "Create", "Update", "Delete", "Rename", ...
+	ID     string
+	OldID  string // Set for renames only
+	Time   time.Time
+}
+
+// Store is a filesystem.Store whose writes are committed to git.
+// No remote is required; the repository lives in the store directory.
+type Store struct {
+	*filesystem.Store
+
+	cfg     Config
+	message *template.Template
+	gitMu   sync.Mutex // Serializes git invocations
+
+	latestMu   sync.Mutex
+	latestHead string                   // HEAD that latest was read at
+	latest     map[string]models.Commit // See latestCommits
+}
+
+// Open wraps the vault at dir, initializing a git repository there if needed.
+func Open(dir string, cfg Config) (*Store, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git executable not found: %w", err)
+	}
+	if cfg.AuthorName == "" {
+		cfg.AuthorName = "Marko"
+	}
+	if cfg.AuthorEmail == "" {
+		cfg.AuthorEmail = "marko@localhost"
+	}
+	if cfg.MessageTemplate == "" {
+		cfg.MessageTemplate = DefaultMessage
+	}
+
+	tmpl, err := template.New("message").Parse(cfg.MessageTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit message template: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &Store{Store: filesystem.NewStore(dir), cfg: cfg, message: tmpl}
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// init creates the repository unless dir already is a repository root.
+// A repository further up the tree (e.g. this project's own) does not count.
+func (s *Store) init() error {
+	if _, err := os.Stat(filepath.Join(s.Dir, ".git")); err == nil {
+		return nil
+	}
+
+	if _, err := s.git("init", "--quiet"); err != nil {
+		return err
+	}
+
+	// Keep internal files out of `git status`
+	ignore := "index.db*\n.history/\n.cache/\n.trash/\n"
+	if err := os.WriteFile(filepath.Join(s.Dir, ".gitignore"), []byte(ignore), 0644); err != nil {
+		return err
+	}
+	// Start the history from the notes already in the vault
+	return s.commit([]string{"."}, MessageData{Action: "Initialize", ID: "vault"})
+}
+
+func (s *Store) Save(id string, content string) error {
+	action := "Update"
+	if _, err := s.Store.Stat(id); os.IsNotExist(err) {
+		action = "Create"
+	}
+
+	if err := s.Store.Save(id, content); err != nil {
+		return err
+	}
+
+	info, err := s.Store.Stat(id)
+	if err != nil {
+		return err
+	}
+	return s.commit([]string{info.ID}, MessageData{Action: action, ID: info.ID})
+}
+
+func (s *Store) SaveIfMatch(id, content, version string) error {
+	if err := s.Store.SaveIfMatch(id, content, version); err != nil {
+		return err
+	}
+
+	info, err := s.Store.Stat(id)
+	if err != nil {
+		return err
+	}
+	return s.commit([]string{info.ID}, MessageData{Action: "Update", ID: info.ID})
+}
+
+func (s *Store) DeleteIfMatch(id, version string) error {
+	info, err := s.Store.Stat(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Store.DeleteIfMatch(id, version); err != nil {
+		return err
+	}
+	return s.commit([]string{info.ID}, MessageData{Action: "Delete", ID: info.ID})
+}
+
+func (s *Store) Delete(id string) error {
+	info, err := s.Store.Stat(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Store.Delete(id); err != nil {
+		return err
+	}
+	return s.commit([]string{info.ID}, MessageData{Action: "Delete", ID: info.ID})
+}
+
+func (s *Store) Rename(oldID, newID string) error {
+	info, err := s.Store.Stat(oldID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Store.Rename(oldID, newID); err != nil {
+		return err
+	}
+
+	renamed, err := s.Store.Stat(newID)
+	if err != nil {
+		return err
+	}
+	return s.commit([]string{info.ID, renamed.ID}, MessageData{Action: "Rename", ID: renamed.ID, OldID: info.ID})
+}
+
+func (s *Store) RenameFolder(oldFolder, newFolder string) error {
+	if err := s.Store.RenameFolder(oldFolder, newFolder); err != nil {
+		return err
+	}
+	// Both paths were validated by the rename above
+	oldFolder, _ = filesystem.CleanID(oldFolder)
+	newFolder, _ = filesystem.CleanID(newFolder)
+	return s.commit([]string{oldFolder, newFolder}, MessageData{Action: "Rename folder", ID: newFolder, OldID: oldFolder})
+}
+
+func (s *Store) DeleteFolder(folder string, recursive bool) error {
+	if err := s.Store.DeleteFolder(folder, recursive); err != nil {
+		return err
+	}
+	folder, _ = filesystem.CleanID(folder)
+	return s.commit([]string{folder}, MessageData{Action: "Delete folder", ID: folder})
+}
+
+// RestoreTrash brings a deleted note back and commits it.
+func (s *Store) RestoreTrash(id string) (string, error) {
+	noteID, err := s.Store.RestoreTrash(id)
+	if err != nil {
+		return "", err
+	}
+	return noteID, s.commit([]string{noteID}, MessageData{Action: "Restore", ID: noteID})
+}
+
+// Get returns the note along with who changed it last.
+func (s *Store) Get(id string) (models.Note, error) {
+	note, err := s.Store.Get(id)
+	if err != nil {
+		return note, err
+	}
+
+	if info, err := s.Store.Stat(id); err == nil {
+		if commits, err := s.log(info.ID, 1); err == nil && len(commits) > 0 {
+			setLastChange(&note, commits[0])
+		}
+	}
+	return note, nil
+}
+
+// List returns all notes with last-change information, using a single
+// git log over the whole repository per commit.
+func (s *Store) List() ([]models.Note, error) {
+	notes, err := s.Store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := s.latestCommits()
+	if err != nil {
+		return notes, nil // Last-change info is best effort
+	}
+	for i := range notes {
+		if c, ok := latest[notes[i].ID]; ok {
+			setLastChange(&notes[i], c)
+		}
+	}
+	return notes, nil
+}
+
+// Log returns the commits that touched a note, newest first, following renames.
+func (s *Store) Log(id string) ([]models.Commit, error) {
+	info, err := s.Store.Stat(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.log(info.ID, 0)
+}
+
+func setLastChange(note *models.Note, c models.Commit) {
+	date := c.Date
+	note.LastChangedBy = c.Author
+	note.LastChangedAt = &date
+	note.LastCommit = c.Hash
+}
+
+// logFormat separates fields with the ASCII unit separator and terminates
+// records with the record separator so messages cannot break parsing.
+const logFormat = "--format=%x1e%H%x1f%an%x1f%ae%x1f%aI%x1f%s"
+
+func (s *Store) log(path string, limit int) ([]models.Commit, error) {
+	args := []string{"log", "--follow", logFormat}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("-n%d", limit))
+	}
+	out, err := s.git(append(args, "--", path)...)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []models.Commit{}
+	for _, record := range strings.Split(out, "\x1e") {
+		if c, ok := parseCommit(record); ok {
+			commits = append(commits, c)
+		}
+	}
+	return commits, nil
+}
+
+// latestCommits maps every path in the history to the newest commit
+// touching it. Walking the history is costly, so the result is kept until
+// HEAD moves.
+func (s *Store) latestCommits() (map[string]models.Commit, error) {
+	head, err := s.git("rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	head = strings.TrimSpace(head)
+
+	s.latestMu.Lock()
+	defer s.latestMu.Unlock()
+	if s.latest != nil && s.latestHead == head {
+		return s.latest, nil
+	}
+
+	// -z separates paths with NUL and leaves non-ASCII paths unquoted, so
+	// they match note IDs
+	out, err := s.git("-c", "core.quotePath=false", "log", "-z", "--name-only", logFormat, head)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[string]models.Commit)
+	for _, record := range strings.Split(out, "\x1e") {
+		header, files, _ := strings.Cut(record, "\x00")
+		c, ok := parseCommit(header)
+		if !ok {
+			continue
+		}
+		for _, f := range strings.Split(strings.TrimPrefix(files, "\n"), "\x00") {
+			if _, seen := latest[f]; f != "" && !seen {
+				latest[f] = c
+			}
+		}
+	}
+	s.latest, s.latestHead = latest, head
+	return latest, nil
+}
+
+func parseCommit(record string) (models.Commit, bool) {
+	fields := strings.Split(strings.TrimSpace(record), "\x1f")
+	if len(fields) != 5 {
+		return models.Commit{}, false
+	}
+	date, _ := time.Parse(time.RFC3339, fields[3])
+	return models.Commit{
+		Hash:    fields[0],
+		Author:  fields[1],
+		Email:   fields[2],
+		Date:    date,
+		Message: fields[4],
+	}, true
+}
+
+// commit stages the given paths (including deletions) and commits them.
+// It is a no-op when the paths have no changes.
+func (s *Store) commit(paths []string, data MessageData) error {
+	if data.Time.IsZero() {
+		data.Time = time.Now()
+	}
+	var msg bytes.Buffer
+	if err := s.message.Execute(&msg, data); err != nil {
+		return fmt.Errorf("render commit message: %w", err)
+	}
+
+	s.gitMu.Lock()
+	defer s.gitMu.Unlock()
+
+	for _, p := range paths {
+		var err error
+		if _, statErr := os.Stat(filepath.Join(s.Dir, filepath.FromSlash(p))); os.IsNotExist(statErr) {
+			// `git add` rejects missing paths that were never tracked
+			_, err = s.git("rm", "-r", "--cached", "--quiet", "--ignore-unmatch", "--", p)
+		} else {
+			_, err = s.git("add", "--all", "--", p)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := s.git(append([]string{"diff", "--cached", "--quiet", "--"}, paths...)...); err == nil {
+		return nil // Nothing staged
+	}
+	_, err := s.git("commit", "--quiet", "--no-verify", "-m", msg.String())
+	return err
+}
+
+func (s *Store) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = s.Dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+s.cfg.AuthorName,
+		"GIT_AUTHOR_EMAIL="+s.cfg.AuthorEmail,
+		"GIT_COMMITTER_NAME="+s.cfg.AuthorName,
+		"GIT_COMMITTER_EMAIL="+s.cfg.AuthorEmail,
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
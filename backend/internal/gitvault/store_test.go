@@ -0,0 +1,103 @@
+package gitvault
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestStore_CommitsChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	store, err := Open(t.TempDir(), Config{
+		AuthorName:      "Alice",
+		AuthorEmail:     "alice@example.com",
+		MessageTemplate: "notes: {{.Action}} {{.ID}}",
+	})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if err := store.Save("go/channels", "# Channels"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := store.Save("go/channels", "# Channels\n\nUpdated"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	// Saving identical content must not fail on an empty commit
+	if err := store.Save("go/channels", "# Channels\n\nUpdated"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	commits, err := store.Log("go/channels")
+	if err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits, got %d", len(commits))
+	}
+	if commits[0].Message != "notes: Update go/channels.md" || commits[1].Message != "notes: Create go/channels.md" {
+		t.Errorf("unexpected messages: %q, %q", commits[0].Message, commits[1].Message)
+	}
+
+	note, err := store.Get("go/channels")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if note.LastChangedBy != "Alice" || note.LastCommit != commits[0].Hash {
+		t.Errorf("unexpected last change: %q %q", note.LastChangedBy, note.LastCommit)
+	}
+
+	if err := store.Rename("go/channels", "go/chans"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if commits, _ := store.Log("go/chans"); len(commits) != 3 {
+		t.Errorf("Expected log to follow the rename, got %d commits", len(commits))
+	}
+
+	notes, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(notes) != 1 || notes[0].LastChangedBy != "Alice" {
+		t.Errorf("unexpected list: %+v", notes)
+	}
+
+	if err := store.Delete("go/chans"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+}
+
+func TestStore_ListNonASCIIPaths(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	store, err := Open(t.TempDir(), Config{AuthorName: "Alice"})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := store.Save("réunions/café", "# Café"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// The second List is served from the cached log
+	for i := 0; i < 2; i++ {
+		notes, err := store.List()
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(notes) != 1 || notes[0].ID != "réunions/café.md" || notes[0].LastChangedBy != "Alice" {
+			t.Errorf("unexpected list: %+v", notes)
+		}
+	}
+
+	if err := store.Save("réunions/café", "# Café\n\nUpdated"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	notes, _ := store.List()
+	if commits, _ := store.Log("réunions/café"); len(notes) != 1 || len(commits) == 0 || notes[0].LastCommit != commits[0].Hash {
+		t.Errorf("expected the list to follow the new commit, got %+v", notes)
+	}
+}
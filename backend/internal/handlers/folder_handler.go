@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"marko-backend/internal/filesystem"
+	"marko-backend/internal/search"
+	"marko-backend/internal/storage"
+)
+
+type FolderHandler struct {
+	Store         storage.NoteRepository
+	SearchService *search.Service
+}
+
+func NewFolderHandler(store storage.NoteRepository, search *search.Service) *FolderHandler {
+	return &FolderHandler{Store: store, SearchService: search}
+}
+
+type folderRequest struct {
+	Path string `json:"path"`
+}
+
+func (h *FolderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	folders, ok := h.Store.(storage.FolderRepository)
+	if !ok {
+		http.Error(w, "Folders are not supported by this storage backend", http.StatusNotImplemented)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/folders"), "/")
+
+	switch r.Method {
+	case http.MethodGet:
+		tree, err := folders.Folders()
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tree)
+	case http.MethodPost:
+		var req folderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := folders.CreateFolder(req.Path); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodPut:
+		var req folderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.renameFolder(w, folders, path, req.Path)
+	case http.MethodDelete:
+		h.deleteFolder(w, folders, path, r.URL.Query().Get("recursive") == "true")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *FolderHandler) renameFolder(w http.ResponseWriter, folders storage.FolderRepository, oldPath, newPath string) {
+	oldClean, err := filesystem.CleanID(oldPath)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	newClean, err := filesystem.CleanID(newPath)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	// Note IDs embed the folder path, so collect them before moving
+	moved, err := folders.NotesIn(oldClean)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if err := folders.RenameFolder(oldClean, newClean); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if h.SearchService != nil {
+		go func() {
+			for _, info := range moved {
+				h.SearchService.Delete(info.ID)
+				newID := newClean + strings.TrimPrefix(info.ID, oldClean)
+				if note, err := h.Store.Get(newID); err == nil {
+					h.SearchService.Index(note)
+				}
+			}
+		}()
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *FolderHandler) deleteFolder(w http.ResponseWriter, folders storage.FolderRepository, path string, recursive bool) {
+	removed, err := folders.NotesIn(path)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if err := folders.DeleteFolder(path, recursive); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if h.SearchService != nil {
+		go func() {
+			for _, info := range removed {
+				h.SearchService.Delete(info.ID)
+			}
+		}()
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"marko-backend/internal/search"
+)
+
+// maxGraphDepth bounds neighbourhood queries.
+const maxGraphDepth = 10
+
+// Graph serves GET /api/graph, the vault's note graph for a graph view.
+// Query parameters:
+//
+//	center=go/basics.md  only the notes around this one
+//	depth=N              how many links away from center (default 1)
+//	tags=false           leave out tag nodes and edges
+//	orphans=true         only notes without any links
+func (h *NoteHandler) Graph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.SearchService == nil {
+		http.Error(w, "Search service invalid/unavailable (check -tags fts5)", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	opts := search.GraphOptions{
+		Depth:   1,
+		Tags:    q.Get("tags") != "false",
+		Orphans: q.Get("orphans") == "true",
+	}
+	if v := q.Get("depth"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxGraphDepth {
+			http.Error(w, "depth must be between 0 and "+strconv.Itoa(maxGraphDepth), http.StatusBadRequest)
+			return
+		}
+		opts.Depth = n
+	}
+	if center := q.Get("center"); center != "" {
+		info, err := h.Store.Stat(center)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		opts.Center = info.ID
+	}
+
+	graph, err := h.SearchService.Graph(opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(graph)
+}
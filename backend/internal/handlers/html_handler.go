@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"marko-backend/internal/markdown"
+)
+
+// NoteHTML serves GET /api/notes/{id}/html: the note body rendered to a
+// sanitized HTML fragment, for consumers that do not render markdown
+// themselves (email, static export, CLI). Frontmatter is not included.
+func (h *NoteHandler) NoteHTML(w http.ResponseWriter, r *http.Request, id string) {
+	note, err := h.Store.Get(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	etag := formatETag(note.Version)
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && containsETag(match, note.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// The fragment needs no scripts or styles of its own if opened directly
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src *")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.WriteString(w, markdown.Render(note.Content))
+}
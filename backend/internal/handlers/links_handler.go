@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// The link index lives in index.db next to the search index, so these
+// endpoints need the search service.
+
+// NoteLinks serves GET /api/notes/{id}/links: the note's outgoing links,
+// with links to missing notes marked broken.
+func (h *NoteHandler) NoteLinks(w http.ResponseWriter, r *http.Request, id string) {
+	noteID, ok := h.linkIndex(w, id)
+	if !ok {
+		return
+	}
+
+	out, err := h.SearchService.Links(noteID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// Backlinks serves GET /api/notes/{id}/backlinks: every link from another
+// note to this one.
+func (h *NoteHandler) Backlinks(w http.ResponseWriter, r *http.Request, id string) {
+	noteID, ok := h.linkIndex(w, id)
+	if !ok {
+		return
+	}
+
+	out, err := h.SearchService.Backlinks(noteID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// BrokenLinks serves GET /api/links/broken: every link in the vault whose
+// target does not exist.
+func (h *NoteHandler) BrokenLinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.SearchService == nil {
+		http.Error(w, "Search service invalid/unavailable (check -tags fts5)", http.StatusServiceUnavailable)
+		return
+	}
+
+	out, err := h.SearchService.BrokenLinks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// linkIndex checks that the link index is available and the note exists,
+// returning its canonical ID.
+func (h *NoteHandler) linkIndex(w http.ResponseWriter, id string) (string, bool) {
+	if h.SearchService == nil {
+		http.Error(w, "Search service invalid/unavailable (check -tags fts5)", http.StatusServiceUnavailable)
+		return "", false
+	}
+	info, err := h.Store.Stat(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return "", false
+	}
+	return info.ID, true
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"marko-backend/internal/models"
+	"marko-backend/internal/search"
+)
+
+// NoteList is the response envelope of GET /api/notes. Total counts every
+// note matching the filters; NextCursor is empty on the last page.
+type NoteList struct {
+	Notes      []models.Note `json:"notes"`
+	Total      int           `json:"total"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+// listOptions are the query parameters of GET /api/notes:
+//
+//	limit=N                        page size (default: all notes)
+//	cursor=...                     nextCursor of the previous page
+//	sort=title|createdAt|updatedAt with order=asc|desc (default asc)
+//	tag=go                         only notes carrying the tag
+//	createdAfter, createdBefore,
+//	updatedAfter, updatedBefore    RFC 3339 or YYYY-MM-DD; After is
+//	                               inclusive, Before is exclusive
+//
+// Without sort, notes keep the store's order.
+type listOptions struct {
+	limit  int
+	offset int
+	sort   string
+	desc   bool
+	tag    string
+
+	createdAfter, createdBefore time.Time
+	updatedAfter, updatedBefore time.Time
+}
+
+var noteSorts = map[string]func(a, b models.Note) int{
+	"title": func(a, b models.Note) int {
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	},
+	"createdAt": func(a, b models.Note) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"updatedAt": func(a, b models.Note) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+}
+
+func parseListOptions(q url.Values) (listOptions, error) {
+	opts := listOptions{sort: q.Get("sort"), tag: q.Get("tag")}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("invalid limit %q", v)
+		}
+		opts.limit = n
+	}
+	if v := q.Get("cursor"); v != "" {
+		n, err := search.DecodeCursor(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid cursor %q", v)
+		}
+		opts.offset = n
+	}
+
+	if _, ok := noteSorts[opts.sort]; opts.sort != "" && !ok {
+		return opts, fmt.Errorf("invalid sort %q (want title, createdAt or updatedAt)", opts.sort)
+	}
+	switch order := q.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		opts.desc = true
+	default:
+		return opts, fmt.Errorf("invalid order %q (want asc or desc)", order)
+	}
+
+	for name, dst := range map[string]*time.Time{
+		"createdAfter":  &opts.createdAfter,
+		"createdBefore": &opts.createdBefore,
+		"updatedAfter":  &opts.updatedAfter,
+		"updatedBefore": &opts.updatedBefore,
+	} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := parseDate(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s %q", name, v)
+		}
+		*dst = t
+	}
+	return opts, nil
+}
+
+func parseDate(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+// match reports whether a note passes the tag and date filters.
+func (o listOptions) match(n models.Note) bool {
+	if o.tag != "" && !hasTag(n, o.tag) {
+		return false
+	}
+	return inRange(n.CreatedAt, o.createdAfter, o.createdBefore) &&
+		inRange(n.UpdatedAt, o.updatedAfter, o.updatedBefore)
+}
+
+func inRange(t, after, before time.Time) bool {
+	if !after.IsZero() && t.Before(after) {
+		return false
+	}
+	if !before.IsZero() && !t.Before(before) {
+		return false
+	}
+	return true
+}
+
+// page filters, sorts and slices notes according to o.
+func (o listOptions) page(notes []models.Note) NoteList {
+	filtered := []models.Note{}
+	for _, n := range notes {
+		if o.match(n) {
+			filtered = append(filtered, n)
+		}
+	}
+
+	if cmp, ok := noteSorts[o.sort]; ok {
+		sort.SliceStable(filtered, func(i, j int) bool {
+			a, b := filtered[i], filtered[j]
+			if o.desc {
+				a, b = b, a
+			}
+			if c := cmp(a, b); c != 0 {
+				return c < 0
+			}
+			return a.ID < b.ID
+		})
+	}
+
+	list := NoteList{Notes: []models.Note{}, Total: len(filtered)}
+	if o.offset >= len(filtered) {
+		return list
+	}
+	end := len(filtered)
+	if o.limit > 0 && o.offset+o.limit < end {
+		end = o.offset + o.limit
+		list.NextCursor = search.EncodeCursor(end)
+	}
+	list.Notes = filtered[o.offset:end]
+	return list
+}
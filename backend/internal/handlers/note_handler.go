@@ -1,23 +1,31 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"marko-backend/internal/filesystem"
+	"marko-backend/internal/markdown"
 	"marko-backend/internal/models"
 	"marko-backend/internal/search"
+	"marko-backend/internal/storage"
 )
 
 type NoteHandler struct {
-	Store         *filesystem.Store
+	Store         storage.NoteRepository
 	SearchService *search.Service
 }
 
-func NewNoteHandler(store *filesystem.Store, search *search.Service) *NoteHandler {
+func NewNoteHandler(store storage.NoteRepository, search *search.Service) *NoteHandler {
 	return &NoteHandler{Store: store, SearchService: search}
 }
 
@@ -31,30 +39,114 @@ func (h *NoteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// For simplicity, let's add a separate SearchHandler method and register it in main.
 	// But sticking to NoteHandler for now.
 
+	id, action, rest := splitNotePath(path)
+	if action != "" {
+		h.serveNoteAction(w, r, id, action, rest)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
-		if path == "" || path == "/" {
+		if id == "" {
 			h.ListNotes(w, r)
 		} else {
-			id := strings.TrimPrefix(path, "/")
 			h.GetNote(w, r, id)
 		}
 	case http.MethodPost:
-		if path == "" || path == "/" {
+		if id == "" {
 			h.CreateNote(w, r)
 		}
 	case http.MethodPut:
-		id := strings.TrimPrefix(path, "/")
 		h.UpdateNote(w, r, id)
 	case http.MethodDelete:
-		id := strings.TrimPrefix(path, "/")
 		h.DeleteNote(w, r, id)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
+// noteActions are the sub-resources that may follow a note ID with its .md
+// extension, e.g. /api/notes/go/concurrency.md/revisions.
+var noteActions = map[string]bool{
+	"revisions": true,
+	"diff":      true,
+	"log":       true,
+	"rename":    true,
+	"links":     true,
+	"backlinks": true,
+	"html":      true,
+	"outline":   true,
+	"tasks":     true,
+}
+
+// splitNotePath splits the part of the URL after /api/notes into a note ID,
+// an optional action and any remaining segments. Since IDs may contain
+// folders, the ID ends at the first segment with a .md extension. Without
+// one the whole path is the ID: a folder may be named like an action, so
+// actions must follow an ID spelled with its extension
+// (/api/notes/work/tasks/todo is the note work/tasks/todo.md).
+func splitNotePath(path string) (id, action string, rest []string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	end := len(segments)
+	for i, seg := range segments {
+		if strings.HasSuffix(seg, ".md") {
+			end = i + 1
+			break
+		}
+	}
+
+	id = strings.Join(segments[:end], "/")
+	if end < len(segments) && noteActions[segments[end]] {
+		return id, segments[end], segments[end+1:]
+	}
+	if end < len(segments) {
+		// Unknown trailing segments: keep them in the ID so lookups 404
+		return strings.Join(segments, "/"), "", nil
+	}
+	return id, "", nil
+}
+
+func (h *NoteHandler) serveNoteAction(w http.ResponseWriter, r *http.Request, id, action string, rest []string) {
+	switch {
+	case action == "revisions" && r.Method == http.MethodGet && len(rest) == 0:
+		h.ListRevisions(w, r, id)
+	case action == "revisions" && r.Method == http.MethodGet && len(rest) == 1:
+		h.GetRevision(w, r, id, rest[0])
+	case action == "revisions" && r.Method == http.MethodPost && len(rest) == 2 && rest[1] == "restore":
+		h.RestoreRevision(w, r, id, rest[0])
+	case action == "diff" && r.Method == http.MethodGet && len(rest) == 0:
+		h.DiffRevisions(w, r, id)
+	case action == "log" && r.Method == http.MethodGet && len(rest) == 0:
+		h.NoteLog(w, r, id)
+	case action == "rename" && r.Method == http.MethodPost && len(rest) == 0:
+		h.RenameNote(w, r, id)
+	case action == "links" && r.Method == http.MethodGet && len(rest) == 0:
+		h.NoteLinks(w, r, id)
+	case action == "backlinks" && r.Method == http.MethodGet && len(rest) == 0:
+		h.Backlinks(w, r, id)
+	case action == "html" && r.Method == http.MethodGet && len(rest) == 0:
+		h.NoteHTML(w, r, id)
+	case action == "outline" && r.Method == http.MethodGet && len(rest) == 0:
+		h.NoteOutline(w, r, id)
+	case action == "outline" && r.Method == http.MethodGet && len(rest) == 1:
+		h.NoteSection(w, r, id, rest[0])
+	case action == "tasks" && r.Method == http.MethodGet && len(rest) == 0:
+		h.NoteTasks(w, r, id)
+	case action == "tasks" && r.Method == http.MethodPatch && len(rest) == 1:
+		h.ToggleTask(w, r, id, rest[0])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
 func (h *NoteHandler) ListNotes(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	notes, err := h.Store.List()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -62,7 +154,7 @@ func (h *NoteHandler) ListNotes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(notes)
+	json.NewEncoder(w).Encode(opts.page(notes))
 }
 
 func (h *NoteHandler) GetNote(w http.ResponseWriter, r *http.Request, id string) {
@@ -72,6 +164,17 @@ func (h *NoteHandler) GetNote(w http.ResponseWriter, r *http.Request, id string)
 		return
 	}
 
+	etag := formatETag(note.Version)
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && containsETag(match, note.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if r.URL.Query().Get("html") == "true" {
+		note.HTML = markdown.Render(note.Content)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(note)
 }
@@ -83,6 +186,8 @@ func (h *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	changes := frontmatterChanges(req, models.Note{})
+
 	// If ID is not provided, try to derive from content or title
 	if req.ID == "" {
 		// First try to parse content to find title
@@ -98,26 +203,36 @@ func (h *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := h.Store.Save(req.ID, req.Content); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Metadata entries from the request become frontmatter keys
+	content, err := filesystem.MergeNoteContent(nil, req.Content, changes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Index asynchronously or synchronously
-	if h.SearchService != nil {
-		// Need full note for indexing (title etc). Parse again or read back.
-		// Since we didn't parse full metadata from req.Content except for ID generation,
-		// let's parse it properly or Read back.
-		// Reading back is safer to stay in sync with what's on disk.
-		go func() {
-			if savedNote, err := h.Store.Get(req.ID); err == nil {
-				h.SearchService.Index(savedNote)
-			}
-		}()
+	if err := h.Store.Save(req.ID, content); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	// Answer with the canonical ID ("my-note.md"): sub-resources such as
+	// /revisions are only routed after an ID with its extension
+	info, err := h.Store.Stat(req.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
 	}
 
+	// Index asynchronously or synchronously
+	// Need full note for indexing (title etc). Parse again or read back.
+	// Since we didn't parse full metadata from req.Content except for ID generation,
+	// let's parse it properly or Read back.
+	// Reading back is safer to stay in sync with what's on disk.
+	h.indexAsync(info.ID)
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"id": req.ID})
+	json.NewEncoder(w).Encode(map[string]string{"id": info.ID})
 }
 
 func (h *NoteHandler) UpdateNote(w http.ResponseWriter, r *http.Request, id string) {
@@ -134,31 +249,80 @@ func (h *NoteHandler) UpdateNote(w http.ResponseWriter, r *http.Request, id stri
 	// We can parse it here to get metadata for Indexing.
 	// OR we can read back from Store. reading back is safer.
 
-	if err := h.Store.Save(id, req.Content); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	version, err := h.matchVersion(r, id)
+	if err != nil {
+		writeStoreError(w, err)
 		return
 	}
 
-	if h.SearchService != nil {
-		go func() {
-			// Read back to get full parsed note
-			if savedNote, err := h.Store.Get(id); err == nil {
-				h.SearchService.Index(savedNote)
-			}
-		}()
+	// The editor sends the body only (GET strips the frontmatter), so keep
+	// the stored frontmatter, unknown keys included. Metadata entries in
+	// the request are set on top of it; a null value removes the key.
+	existing, err := h.Store.Raw(id)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		writeStoreError(w, err)
+		return
+	}
+	stored := filesystem.ParseNoteContent(id, existing, time.Time{})
+	content, err := filesystem.MergeNoteContent(existing, req.Content, frontmatterChanges(req, stored))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	// Without If-Match, still refuse to save over an edit that landed
+	// after the frontmatter above was read
+	if version == "" && existing != nil {
+		version = filesystem.ContentVersion(existing)
+	}
+	if version != "" {
+		err = h.Store.SaveIfMatch(id, content, version)
+	} else {
+		err = h.Store.Save(id, content)
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	info, err := h.Store.Stat(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	h.indexAsync(info.ID)
+
+	w.Header().Set("ETag", formatETag(filesystem.ContentVersion([]byte(content))))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"id": info.ID})
 }
 
 func (h *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request, id string) {
-	if err := h.Store.Delete(id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	version, err := h.matchVersion(r, id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	// The index knows the note by its canonical ID ("my-note.md")
+	info, err := h.Store.Stat(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if version != "" {
+		err = h.Store.DeleteIfMatch(id, version)
+	} else {
+		err = h.Store.Delete(id)
+	}
+	if err != nil {
+		writeStoreError(w, err)
 		return
 	}
 
 	if h.SearchService != nil {
-		go h.SearchService.Delete(id)
+		go h.SearchService.Delete(info.ID)
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -177,7 +341,25 @@ func (h *NoteHandler) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	results, err := h.SearchService.Search(query)
+	opts, err := searchOptions(r.URL.Query(), h.SearchService.Ranking())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.SearchService.SearchPage(query, opts)
+	var queryErr *search.QueryError
+	if errors.As(err, &queryErr) {
+		// Structured, so the search box can point at the problem
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(queryErr)
+		return
+	}
+	if errors.Is(err, search.ErrInvalidSort) || errors.Is(err, search.ErrInvalidCursor) || errors.Is(err, search.ErrInvalidRanking) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -187,6 +369,180 @@ func (h *NoteHandler) Search(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(results)
 }
 
+// searchOptions reads limit, offset, cursor, sort, code (true to search
+// fenced code blocks only), fuzzy (true to match close spellings) and
+// prefix (true to match the last word as a prefix) from the query string,
+// along with overrides of the ranking base:
+//
+//	weights=title:20,body:0.5   column weights (title, tags, headings,
+//	                            body, metadata, code)
+//	recency=2                   recency boost, 0 to turn it off
+//	halfLife=168h               age at which the boost halves
+func searchOptions(q url.Values, base search.Ranking) (search.SearchOptions, error) {
+	opts := search.SearchOptions{
+		Cursor:   q.Get("cursor"),
+		Sort:     q.Get("sort"),
+		CodeOnly: q.Get("code") == "true",
+		Fuzzy:    q.Get("fuzzy") == "true",
+		Prefix:   q.Get("prefix") == "true",
+	}
+	for name, dst := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("invalid %s %q", name, v)
+		}
+		*dst = n
+	}
+
+	if q.Get("weights") == "" && q.Get("recency") == "" && q.Get("halfLife") == "" {
+		return opts, nil
+	}
+	ranking := base
+	weights := ranking.Weights()
+	if v := q.Get("weights"); v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(pair, ":")
+			dst, ok := weights[strings.TrimSpace(name)]
+			if !ok {
+				return opts, fmt.Errorf("invalid weight %q", pair)
+			}
+			w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return opts, fmt.Errorf("invalid weight %q", pair)
+			}
+			*dst = w
+		}
+	}
+	if v := q.Get("recency"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return opts, fmt.Errorf("invalid recency %q", v)
+		}
+		ranking.Recency = f
+	}
+	if v := q.Get("halfLife"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid halfLife %q", v)
+		}
+		ranking.HalfLife = d
+	}
+	opts.Ranking = &ranking
+	return opts, nil
+}
+
+// frontmatterChanges collects the frontmatter keys a create or update
+// request sets explicitly: tags, author and free-form metadata. Values
+// equal to those of stored, the note as it is now, are left out, so a
+// client sending back what GET returned does not reformat them.
+func frontmatterChanges(req, stored models.Note) map[string]any {
+	changes := make(map[string]any, len(req.Metadata)+2)
+	for k, v := range req.Metadata {
+		old, ok := stored.Metadata[k]
+		if v == nil && !ok || v != nil && ok && sameJSON(old, v) {
+			continue
+		}
+		changes[k] = v
+	}
+	if req.Tags != nil && !slices.Equal(req.Tags, stored.Tags) {
+		changes["tags"] = req.Tags
+	}
+	if req.Author != "" && req.Author != stored.Author {
+		changes["author"] = req.Author
+	}
+	return changes
+}
+
+// sameJSON reports whether a and b encode to the same JSON, which evens
+// out the types YAML and JSON decoding pick for the same value.
+func sameJSON(a, b any) bool {
+	x, errX := json.Marshal(a)
+	y, errY := json.Marshal(b)
+	return errX == nil && errY == nil && bytes.Equal(x, y)
+}
+
+// indexAsync reads a note back from the store and indexes it in the
+// background, under its canonical ID ("my-note.md", not "my-note") like
+// Sync and the watcher do.
+func (h *NoteHandler) indexAsync(id string) {
+	if h.SearchService == nil {
+		return
+	}
+	go func() {
+		info, err := h.Store.Stat(id)
+		if err != nil {
+			return
+		}
+		// Read back to get full parsed note
+		if savedNote, err := h.Store.Get(info.ID); err == nil {
+			h.SearchService.Index(savedNote)
+		}
+	}()
+}
+
+// matchVersion evaluates the If-Match header of a write request against the
+// stored note. It returns the version to pass to a conditional write, or ""
+// if the request carries no precondition.
+func (h *NoteHandler) matchVersion(r *http.Request, id string) (string, error) {
+	match := r.Header.Get("If-Match")
+	if match == "" {
+		return "", nil
+	}
+
+	note, err := h.Store.Get(id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", storage.ErrVersionConflict // If-Match never matches a missing note
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(match) == "*" || containsETag(match, note.Version) {
+		return note.Version, nil
+	}
+	return "", storage.ErrVersionConflict
+}
+
+func formatETag(version string) string {
+	return `"` + version + `"`
+}
+
+// containsETag reports whether a comma-separated If-Match/If-None-Match
+// header lists the given version. Weak validators are compared by value.
+func containsETag(header, version string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		tag = strings.TrimPrefix(tag, "W/")
+		if strings.Trim(tag, `"`) == version {
+			return true
+		}
+	}
+	return false
+}
+
+// writeStoreError maps storage errors onto HTTP status codes.
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, filesystem.ErrInvalidPath):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, storage.ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, filesystem.ErrNoTask):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, storage.ErrExists), errors.Is(err, filesystem.ErrNotEmpty):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func slugify(s string) string {
 	s = strings.ToLower(s)
 	s = strings.ReplaceAll(s, " ", "-")
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"marko-backend/internal/filesystem"
+	"marko-backend/internal/search"
+)
+
+// newTestHandler serves the note API from a filesystem store in a temp dir,
+// without a search service.
+func newTestHandler(t *testing.T) (*NoteHandler, *filesystem.Store) {
+	t.Helper()
+
+	store := filesystem.NewStore(t.TempDir())
+	return NewNoteHandler(store, nil), store
+}
+
+// newSearchHandler is newTestHandler with a search service. The FTS5 module
+// is only compiled into go-sqlite3 with -tags sqlite_fts5, so tests using
+// it skip without.
+func newSearchHandler(t *testing.T) (*NoteHandler, *filesystem.Store) {
+	t.Helper()
+
+	h, store := newTestHandler(t)
+	s, err := search.NewService(t.TempDir(), search.Options{})
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			t.Skip("FTS5 not available; run tests with -tags sqlite_fts5")
+		}
+		t.Fatalf("NewService failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	h.SearchService = s
+	return h, store
+}
+
+// expectSearch waits for the background indexing of the handlers to
+// settle and reports an error unless query finds exactly the notes want.
+func expectSearch(t *testing.T, s *search.Service, query string, want ...string) {
+	t.Helper()
+
+	var ids []string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		results, err := s.Search(query)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", query, err)
+		}
+		ids = ids[:0]
+		for _, n := range results {
+			ids = append(ids, n.ID)
+		}
+		if strings.Join(ids, ",") == strings.Join(want, ",") {
+			return
+		}
+	}
+	t.Errorf("Search(%q) = %v, want %v", query, ids, want)
+}
+
+// request sends a request to h and returns the recorded response. headers
+// are given as name, value pairs.
+func request(h http.Handler, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestNoteHandler_ETags(t *testing.T) {
+	h, store := newTestHandler(t)
+	if err := store.Save("draft", "# Draft\n\nFirst"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := request(h, http.MethodGet, "/api/notes/draft.md", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("GET = %d with ETag %q", w.Code, etag)
+	}
+	if w := request(h, http.MethodGet, "/api/notes/draft.md", "", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("GET If-None-Match = %d, want 304", w.Code)
+	}
+
+	// A write from a stale copy is refused and leaves the note alone
+	w = request(h, http.MethodPut, "/api/notes/draft.md", `{"content":"Stale"}`, "If-Match", `"stale"`)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT with stale If-Match = %d, want 412", w.Code)
+	}
+	if note, _ := store.Get("draft.md"); note.Content != "# Draft\n\nFirst" {
+		t.Errorf("stale PUT changed the note: %q", note.Content)
+	}
+
+	w = request(h, http.MethodPut, "/api/notes/draft.md", `{"content":"# Draft\n\nSecond"}`, "If-Match", etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT with current If-Match = %d: %s", w.Code, w.Body)
+	}
+	next := w.Header().Get("ETag")
+	if next == etag || next != request(h, http.MethodGet, "/api/notes/draft.md", "").Header().Get("ETag") {
+		t.Errorf("PUT returned ETag %q, want the new version's", next)
+	}
+
+	if w := request(h, http.MethodDelete, "/api/notes/draft.md", "", "If-Match", etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with stale If-Match = %d, want 412", w.Code)
+	}
+	if w := request(h, http.MethodPut, "/api/notes/missing.md", `{"content":"x"}`, "If-Match", "*"); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT If-Match on a missing note = %d, want 412", w.Code)
+	}
+
+	// If-Match is optional: plain writes still go through
+	if w := request(h, http.MethodPut, "/api/notes/draft.md", `{"content":"Third"}`); w.Code != http.StatusOK {
+		t.Errorf("PUT without If-Match = %d, want 200", w.Code)
+	}
+	if w := request(h, http.MethodDelete, "/api/notes/draft.md", "", "If-Match", "*"); w.Code != http.StatusOK {
+		t.Errorf("DELETE If-Match * = %d, want 200", w.Code)
+	}
+}
+
+func TestNoteHandler_IndexesCanonicalIDs(t *testing.T) {
+	h, store := newSearchHandler(t)
+
+	w := request(h, http.MethodPost, "/api/notes", `{"title":"My Note","content":"About zebras"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST = %d: %s", w.Code, w.Body)
+	}
+	expectSearch(t, h.SearchService, "zebras", "my-note.md")
+
+	// The note is indexed once, under the ID Sync and the watcher use
+	if w := request(h, http.MethodPut, "/api/notes/my-note", `{"content":"About giraffes"}`); w.Code != http.StatusOK {
+		t.Fatalf("PUT = %d: %s", w.Code, w.Body)
+	}
+	expectSearch(t, h.SearchService, "giraffes", "my-note.md")
+	stats, err := h.SearchService.Sync(store)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if stats.Added != 0 || stats.Removed != 0 {
+		t.Errorf("Sync after API writes = %+v, want nothing added or removed", stats)
+	}
+}
+
+func TestNoteHandler_DeleteRemovesFromSearch(t *testing.T) {
+	h, _ := newSearchHandler(t)
+
+	if w := request(h, http.MethodPost, "/api/notes", `{"title":"My Note","content":"About zebras"}`); w.Code != http.StatusCreated {
+		t.Fatalf("POST = %d: %s", w.Code, w.Body)
+	}
+	expectSearch(t, h.SearchService, "zebras", "my-note.md")
+
+	// Deleted by the ID without extension, trashed notes are not found
+	if w := request(h, http.MethodDelete, "/api/notes/my-note", ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE = %d: %s", w.Code, w.Body)
+	}
+	expectSearch(t, h.SearchService, "zebras")
+	if w := request(h, http.MethodDelete, "/api/notes/my-note", ""); w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE = %d, want 404", w.Code)
+	}
+}
+
+func TestSplitNotePath(t *testing.T) {
+	tests := []struct {
+		path, id, action string
+		rest             []string
+	}{
+		{"/my-note.md/revisions", "my-note.md", "revisions", nil},
+		{"/my-note.md/revisions/3/restore", "my-note.md", "revisions", []string{"3", "restore"}},
+		{"/go/channels.md/tasks/4", "go/channels.md", "tasks", []string{"4"}},
+		// Without the extension the whole path is the ID, so folders may
+		// be named like actions
+		{"/my-note", "my-note", "", nil},
+		{"/my-note/revisions", "my-note/revisions", "", nil},
+		{"/work/tasks/todo", "work/tasks/todo", "", nil},
+		{"/my-note.md/unknown", "my-note.md/unknown", "", nil},
+	}
+	for _, tt := range tests {
+		id, action, rest := splitNotePath(tt.path)
+		if id != tt.id || action != tt.action || strings.Join(rest, "/") != strings.Join(tt.rest, "/") {
+			t.Errorf("splitNotePath(%q) = %q, %q, %v; want %q, %q, %v", tt.path, id, action, rest, tt.id, tt.action, tt.rest)
+		}
+	}
+}
+
+func TestNoteHandler_ReturnsRoutableIDs(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	// The ID a write returns reaches the note's sub-resources
+	w := request(h, http.MethodPost, "/api/notes", `{"title":"My Note","content":"First"}`)
+	var created map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&created); w.Code != http.StatusCreated || err != nil || created["id"] != "my-note.md" {
+		t.Fatalf("POST = %d, %v, %v; want id my-note.md", w.Code, created, err)
+	}
+	w = request(h, http.MethodPut, "/api/notes/my-note", `{"content":"Second"}`)
+	var updated map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&updated); w.Code != http.StatusOK || err != nil || updated["id"] != "my-note.md" {
+		t.Fatalf("PUT = %d, %v, %v; want id my-note.md", w.Code, updated, err)
+	}
+	if w := request(h, http.MethodGet, "/api/notes/"+updated["id"]+"/revisions", ""); w.Code != http.StatusOK {
+		t.Errorf("GET revisions = %d, want 200", w.Code)
+	}
+	if w := request(h, http.MethodGet, "/api/notes/my-note/revisions", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET revisions without extension = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"marko-backend/internal/markdown"
+	"marko-backend/internal/models"
+)
+
+// NoteOutline serves GET /api/notes/{id}/outline: the note's headings with
+// their levels, anchors and line numbers.
+func (h *NoteHandler) NoteOutline(w http.ResponseWriter, r *http.Request, id string) {
+	note, err := h.Store.Get(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	outline := note.Outline
+	if outline == nil {
+		outline = []models.Heading{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(outline)
+}
+
+// NoteSection serves GET /api/notes/{id}/outline/{anchor}: the markdown
+// under one heading, up to the next heading of the same or a higher level.
+// With ?html=true the section is also rendered.
+func (h *NoteHandler) NoteSection(w http.ResponseWriter, r *http.Request, id, anchor string) {
+	note, err := h.Store.Get(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	heading, content, ok := markdown.Section(note.Content, anchor)
+	if !ok {
+		http.Error(w, "No heading with anchor "+anchor, http.StatusNotFound)
+		return
+	}
+
+	section := models.Section{Content: content}
+	for _, h := range note.Outline {
+		if h.Anchor == heading.Anchor {
+			section.Heading = h // line numbers relative to the file
+			break
+		}
+	}
+	if r.URL.Query().Get("html") == "true" {
+		section.HTML = markdown.Render(content)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(section)
+}
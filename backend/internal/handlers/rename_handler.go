@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"marko-backend/internal/filesystem"
+	"marko-backend/internal/links"
+)
+
+type renameRequest struct {
+	// ID is the new note ID, e.g. "go/concurrency". If empty, the note
+	// keeps its folder and the new name is derived from Title.
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// RewriteLinks updates wikilinks and relative markdown links in other
+	// notes that point to the old ID.
+	RewriteLinks bool `json:"rewriteLinks"`
+}
+
+type renameResponse struct {
+	ID      string          `json:"id"`
+	OldID   string          `json:"oldId"`
+	Updated []string        `json:"updated"`
+	Failed  []renameFailure `json:"failed,omitempty"`
+}
+
+// renameFailure is a note whose links could not be rewritten, e.g.
+// because it was edited while the rename ran.
+type renameFailure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// RenameNote serves POST /api/notes/{id}/rename: it moves the note to a new
+// ID, moves its search index entry and optionally rewrites links to it.
+func (h *NoteHandler) RenameNote(w http.ResponseWriter, r *http.Request, id string) {
+	var req renameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	info, err := h.Store.Stat(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	oldID := info.ID
+
+	newID := req.ID
+	if newID == "" {
+		if strings.TrimSpace(req.Title) == "" {
+			http.Error(w, "id or title required", http.StatusBadRequest)
+			return
+		}
+		newID = path.Join(path.Dir(oldID), slugify(req.Title))
+	}
+	newID, err = filesystem.CleanID(newID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if !strings.HasSuffix(newID, ".md") {
+		newID += ".md"
+	}
+
+	resp := renameResponse{ID: newID, OldID: oldID, Updated: []string{}}
+	if newID == oldID {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	// Resolve links against the vault as it is before the move
+	var resolver *links.Resolver
+	var notes []string
+	if req.RewriteLinks {
+		list, err := h.Store.List()
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		for _, n := range list {
+			notes = append(notes, n.ID)
+		}
+		resolver = links.NewResolver(notes)
+	}
+
+	if err := h.Store.Rename(oldID, newID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if h.SearchService != nil {
+		go h.SearchService.Delete(oldID)
+	}
+	h.indexAsync(newID)
+
+	if req.RewriteLinks {
+		resp.Updated, resp.Failed = h.rewriteLinks(resolver, notes, oldID, newID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// rewriteLinks points every link to oldID at newID and returns the IDs of
+// the notes it changed and of those it failed to change. The note itself
+// (now at newID) has its relative links adjusted to its new folder. Notes
+// are only saved if unchanged since they were read, so a concurrent edit
+// is never overwritten. Failures are logged and skipped: the rename has
+// already happened and the remaining notes should still be fixed.
+func (h *NoteHandler) rewriteLinks(resolver *links.Resolver, notes []string, oldID, newID string) ([]string, []renameFailure) {
+	updated := []string{}
+	var failed []renameFailure
+	for _, id := range notes {
+		raw, err := h.Store.Raw(id)
+		if id == oldID {
+			id = newID
+			raw, err = h.Store.Raw(newID)
+		}
+		if err != nil {
+			log.Printf("Rename %s: reading %s: %v", oldID, id, err)
+			failed = append(failed, renameFailure{ID: id, Error: err.Error()})
+			continue
+		}
+
+		var content string
+		var n int
+		if id == newID {
+			content, n = links.Move(string(raw), oldID, newID, resolver)
+		} else {
+			content, n = links.Rewrite(string(raw), id, oldID, newID, resolver)
+		}
+		if n == 0 {
+			continue
+		}
+		if err := h.Store.SaveIfMatch(id, content, filesystem.ContentVersion(raw)); err != nil {
+			log.Printf("Rename %s: updating links in %s: %v", oldID, id, err)
+			failed = append(failed, renameFailure{ID: id, Error: err.Error()})
+			continue
+		}
+		h.indexAsync(id)
+		updated = append(updated, id)
+	}
+	return updated, failed
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNoteHandler_RenameRewritesLinks(t *testing.T) {
+	h, store := newTestHandler(t)
+	for id, content := range map[string]string{
+		"go/basics": "# Basics",
+		"ref":       "See [[go/basics]] and [the basics](go/basics.md).",
+		"other":     "# Other",
+	} {
+		if err := store.Save(id, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := request(h, http.MethodPost, "/api/notes/go/basics.md/rename", `{"id":"go/intro","rewriteLinks":true}`)
+	var resp renameResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("rename = %d, %v", w.Code, err)
+	}
+	if resp.ID != "go/intro.md" || resp.OldID != "go/basics.md" || strings.Join(resp.Updated, ",") != "ref.md" || len(resp.Failed) != 0 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if raw, _ := store.Raw("ref"); string(raw) != "See [[go/intro]] and [the basics](go/intro.md)." {
+		t.Errorf("links not rewritten: %q", raw)
+	}
+	if _, err := store.Get("go/basics"); err == nil {
+		t.Error("old ID still exists")
+	}
+
+	// Renaming onto an existing note is refused and changes nothing
+	w = request(h, http.MethodPost, "/api/notes/go/intro.md/rename", `{"id":"other","rewriteLinks":true}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("rename onto an existing note = %d, want 409", w.Code)
+	}
+	if raw, _ := store.Raw("ref"); !strings.Contains(string(raw), "[[go/intro]]") {
+		t.Errorf("failed rename rewrote links: %q", raw)
+	}
+	if note, err := store.Get("other"); err != nil || note.Content != "# Other" {
+		t.Errorf("failed rename changed the target: %q, %v", note.Content, err)
+	}
+}
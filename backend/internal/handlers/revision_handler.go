@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"marko-backend/internal/diff"
+	"marko-backend/internal/storage"
+)
+
+// revisionStore returns the store's revision support, writing a 501 if the
+// configured backend does not keep history.
+func (h *NoteHandler) revisionStore(w http.ResponseWriter) (storage.RevisionRepository, bool) {
+	revs, ok := h.Store.(storage.RevisionRepository)
+	if !ok {
+		http.Error(w, "Revision history is not supported by this storage backend", http.StatusNotImplemented)
+	}
+	return revs, ok
+}
+
+func (h *NoteHandler) ListRevisions(w http.ResponseWriter, r *http.Request, id string) {
+	store, ok := h.revisionStore(w)
+	if !ok {
+		return
+	}
+
+	revs, err := store.Revisions(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revs)
+}
+
+func (h *NoteHandler) GetRevision(w http.ResponseWriter, r *http.Request, id, rev string) {
+	store, ok := h.revisionStore(w)
+	if !ok {
+		return
+	}
+
+	content, err := store.GetRevision(id, rev)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"id": rev, "content": content})
+}
+
+// DiffRevisions serves a unified diff between two revisions. Without
+// parameters it compares the latest revision with the one before it;
+// "to" defaults to the latest revision.
+func (h *NoteHandler) DiffRevisions(w http.ResponseWriter, r *http.Request, id string) {
+	store, ok := h.revisionStore(w)
+	if !ok {
+		return
+	}
+
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if from == "" || to == "" {
+		revs, err := store.Revisions(id)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if to == "" && len(revs) > 0 {
+			to = revs[0].ID
+		}
+		if from == "" && len(revs) > 1 {
+			from = revs[1].ID
+		}
+	}
+	if from == "" || to == "" {
+		http.Error(w, "Two revisions are required for a diff", http.StatusBadRequest)
+		return
+	}
+
+	a, err := store.GetRevision(id, from)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	b, err := store.GetRevision(id, to)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+	w.Write([]byte(diff.Unified(id+"@"+from, id+"@"+to, a, b, 3)))
+}
+
+// RestoreRevision saves an old revision as the current content. It goes
+// through the normal save path, so the restore itself becomes a revision.
+func (h *NoteHandler) RestoreRevision(w http.ResponseWriter, r *http.Request, id, rev string) {
+	store, ok := h.revisionStore(w)
+	if !ok {
+		return
+	}
+
+	content, err := store.GetRevision(id, rev)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if err := h.Store.Save(id, content); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	h.indexAsync(id)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// NoteLog lists the version-control commits that touched a note.
+func (h *NoteHandler) NoteLog(w http.ResponseWriter, r *http.Request, id string) {
+	store, ok := h.Store.(storage.CommitLogRepository)
+	if !ok {
+		http.Error(w, "Commit log requires the git storage backend", http.StatusNotImplemented)
+		return
+	}
+
+	commits, err := store.Log(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(commits)
+}
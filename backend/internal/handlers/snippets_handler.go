@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"marko-backend/internal/search"
+)
+
+// ListSnippets serves GET /api/snippets, the fenced code blocks of every
+// note with the heading above them. Query parameters:
+//
+//	lang=go      only blocks in that language
+//	q=words      only blocks whose code or heading contains every word
+func (h *NoteHandler) ListSnippets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.SearchService == nil {
+		http.Error(w, "Search service invalid/unavailable (check -tags fts5)", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	snippets, err := h.SearchService.Snippets(search.SnippetFilter{Lang: q.Get("lang"), Query: q.Get("q")})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snippets)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"marko-backend/internal/models"
+)
+
+// ListTags serves GET /api/tags: every tag in the vault with the number of
+// notes carrying it, most used first. Tags are compared case-insensitively
+// and reported with their first spelling.
+func (h *NoteHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	notes, err := h.Store.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(countTags(notes))
+}
+
+func countTags(notes []models.Note) []models.TagCount {
+	index := make(map[string]int)
+	tags := []models.TagCount{}
+	for _, n := range notes {
+		seen := make(map[string]bool)
+		for _, t := range n.Tags {
+			key := strings.ToLower(t)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			if i, ok := index[key]; ok {
+				tags[i].Count++
+				continue
+			}
+			index[key] = len(tags)
+			tags = append(tags, models.TagCount{Tag: t, Count: 1})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return strings.ToLower(tags[i].Tag) < strings.ToLower(tags[j].Tag)
+	})
+	return tags
+}
+
+// hasTag reports whether a note carries tag, ignoring case and a leading '#'.
+func hasTag(note models.Note, tag string) bool {
+	tag = strings.TrimPrefix(tag, "#")
+	for _, t := range note.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
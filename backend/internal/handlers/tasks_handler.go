@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"marko-backend/internal/filesystem"
+	"marko-backend/internal/models"
+	"marko-backend/internal/search"
+)
+
+// ListTasks serves GET /api/tasks, the task list items of every note.
+// Query parameters:
+//
+//	status=open|done     only open or done tasks (default both)
+//	due=true             only tasks with a due date
+//	due=2026-11-01       only tasks due on or before that day
+//	overdue=true         only open tasks due before today
+//	tag=travel           only tasks with that #tag or in notes tagged so
+func (h *NoteHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.SearchService == nil {
+		http.Error(w, "Search service invalid/unavailable (check -tags fts5)", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	var f search.TaskFilter
+	switch q.Get("status") {
+	case "", "all":
+	case "open":
+		f.Done = new(bool)
+	case "done":
+		done := true
+		f.Done = &done
+	default:
+		http.Error(w, "status must be open, done or all", http.StatusBadRequest)
+		return
+	}
+	switch due := q.Get("due"); due {
+	case "", "false":
+	case "true":
+		f.HasDue = true
+	default:
+		day, err := time.Parse("2006-01-02", due)
+		if err != nil {
+			http.Error(w, "due must be true or a date (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		f.DueBefore = day.AddDate(0, 0, 1).Format("2006-01-02")
+	}
+	if q.Get("overdue") == "true" {
+		f.Done = new(bool)
+		if today := time.Now().Format("2006-01-02"); f.DueBefore == "" || today < f.DueBefore {
+			f.DueBefore = today
+		}
+	}
+	f.Tag = q.Get("tag")
+
+	tasks, err := h.SearchService.Tasks(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tasks)
+}
+
+// NoteTasks serves GET /api/notes/{id}/tasks: the note's task list items.
+func (h *NoteHandler) NoteTasks(w http.ResponseWriter, r *http.Request, id string) {
+	note, err := h.Store.Get(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	tasks := note.Tasks
+	if tasks == nil {
+		tasks = []models.Task{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tasks)
+}
+
+type toggleTaskRequest struct {
+	// Done sets the task's state; without it the task is toggled
+	Done *bool `json:"done"`
+}
+
+// ToggleTask serves PATCH /api/notes/{id}/tasks/{line}: it checks or
+// unchecks the task on that line of the file, leaving every other line
+// as it is. If-Match is honoured; without it the write still fails with
+// 412 if the note changes between reading and writing.
+func (h *NoteHandler) ToggleTask(w http.ResponseWriter, r *http.Request, id, lineParam string) {
+	line, err := strconv.Atoi(lineParam)
+	if err != nil || line < 1 {
+		http.Error(w, "line must be a positive number", http.StatusBadRequest)
+		return
+	}
+	var req toggleTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	info, err := h.Store.Stat(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	version, err := h.matchVersion(r, info.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	raw, err := h.Store.Raw(info.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if version == "" {
+		version = filesystem.ContentVersion(raw)
+	}
+
+	content, task, err := filesystem.ToggleTask(info.ID, raw, line, req.Done)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if err := h.Store.SaveIfMatch(info.ID, content, version); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	h.indexAsync(info.ID)
+
+	task.NoteID = info.ID
+	w.Header().Set("ETag", formatETag(filesystem.ContentVersion([]byte(content))))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
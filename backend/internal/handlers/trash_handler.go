@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"marko-backend/internal/search"
+	"marko-backend/internal/storage"
+)
+
+// TrashHandler serves the trash of soft-deleted notes:
+//
+//	GET    /api/trash               list trashed notes, newest first
+//	DELETE /api/trash               empty the trash
+//	POST   /api/trash/{id}/restore  move a note back to where it was
+//	DELETE /api/trash/{id}          delete a trashed note for good
+type TrashHandler struct {
+	Store         storage.NoteRepository
+	SearchService *search.Service
+}
+
+func NewTrashHandler(store storage.NoteRepository, search *search.Service) *TrashHandler {
+	return &TrashHandler{Store: store, SearchService: search}
+}
+
+func (h *TrashHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	trash, ok := h.Store.(storage.TrashRepository)
+	if !ok {
+		http.Error(w, "Trash is not supported by this storage backend", http.StatusNotImplemented)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/trash"), "/")
+
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		items, err := trash.Trash()
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(items)
+	case id == "" && r.Method == http.MethodDelete:
+		n, err := trash.EmptyTrash(time.Time{})
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"purged": n})
+	case strings.HasSuffix(id, "/restore") && r.Method == http.MethodPost:
+		h.restore(w, trash, strings.TrimSuffix(id, "/restore"))
+	case id != "" && r.Method == http.MethodDelete:
+		if err := trash.PurgeTrash(id); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *TrashHandler) restore(w http.ResponseWriter, trash storage.TrashRepository, id string) {
+	noteID, err := trash.RestoreTrash(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if h.SearchService != nil {
+		go func() {
+			if note, err := h.Store.Get(noteID); err == nil {
+				h.SearchService.Index(note)
+			}
+		}()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"id": noteID})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"marko-backend/internal/models"
+)
+
+func TestTrashHandler(t *testing.T) {
+	notes, store := newTestHandler(t)
+	h := NewTrashHandler(store, nil)
+	for id, content := range map[string]string{"go/a": "# A\n\nOriginal", "b": "# B"} {
+		if err := store.Save(id, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := func() []models.TrashItem {
+		t.Helper()
+		w := request(h, http.MethodGet, "/api/trash", "")
+		var items []models.TrashItem
+		if err := json.NewDecoder(w.Body).Decode(&items); w.Code != http.StatusOK || err != nil {
+			t.Fatalf("GET /api/trash = %d, %v", w.Code, err)
+		}
+		return items
+	}
+
+	if w := request(notes, http.MethodDelete, "/api/notes/go/a.md", ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE note = %d", w.Code)
+	}
+	items := list()
+	if len(items) != 1 || items[0].NoteID != "go/a.md" || items[0].Title != "A" {
+		t.Fatalf("unexpected trash: %+v", items)
+	}
+
+	// Restoring never overwrites a note created since
+	if err := store.Save("go/a", "# A\n\nNew"); err != nil {
+		t.Fatal(err)
+	}
+	if w := request(h, http.MethodPost, "/api/trash/"+items[0].ID+"/restore", ""); w.Code != http.StatusConflict {
+		t.Errorf("restore over an existing note = %d, want 409", w.Code)
+	}
+	if err := store.Delete("go/a"); err != nil {
+		t.Fatal(err)
+	}
+	w := request(h, http.MethodPost, "/api/trash/"+items[0].ID+"/restore", "")
+	var restored map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&restored); w.Code != http.StatusOK || err != nil || restored["id"] != "go/a.md" {
+		t.Fatalf("restore = %d, %v, %v", w.Code, restored, err)
+	}
+	if note, err := store.Get("go/a.md"); err != nil || note.Content != "# A\n\nOriginal" {
+		t.Errorf("restored note = %q, %v", note.Content, err)
+	}
+
+	if w := request(notes, http.MethodDelete, "/api/notes/b.md", ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE note = %d", w.Code)
+	}
+	items = list()
+	if len(items) != 2 || items[0].NoteID != "b.md" {
+		t.Fatalf("unexpected trash: %+v", items)
+	}
+	if w := request(h, http.MethodDelete, "/api/trash/"+items[0].ID, ""); w.Code != http.StatusNoContent {
+		t.Errorf("purge = %d, want 204", w.Code)
+	}
+	if w := request(h, http.MethodDelete, "/api/trash/"+items[0].ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("second purge = %d, want 404", w.Code)
+	}
+
+	w = request(h, http.MethodDelete, "/api/trash", "")
+	var emptied map[string]int
+	if err := json.NewDecoder(w.Body).Decode(&emptied); w.Code != http.StatusOK || err != nil || emptied["purged"] != 1 {
+		t.Errorf("empty trash = %d, %v, %v", w.Code, emptied, err)
+	}
+	if items := list(); len(items) != 0 {
+		t.Errorf("trash not empty: %+v", items)
+	}
+}
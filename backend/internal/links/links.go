@@ -0,0 +1,317 @@
+// Package links finds and rewrites links between notes: [[wikilinks]] and
+// markdown links to relative paths. Links inside fenced code blocks and
+// inline code spans are ignored.
+package links
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Kind distinguishes the two link syntaxes.
+type Kind int
+
+const (
+	Wiki     Kind = iota // [[target#heading|alias]]
+	Markdown             // [text](target#anchor)
+)
+
+func (k Kind) String() string {
+	if k == Wiki {
+		return "wiki"
+	}
+	return "markdown"
+}
+
+// Link is one link found in a note. Start and End are the byte offsets of
+// Target within the scanned content, so it can be replaced in place.
+type Link struct {
+	Kind   Kind
+	Target string // as written, without anchor or alias
+	Anchor string // heading or block after '#', if any
+	Text   string // alias or link text, if any
+	Line   int    // 1-based line number
+	Start  int
+	End    int
+}
+
+// Scan returns every link in content, in order of appearance.
+func Scan(content string) []Link {
+	var found []Link
+	offset := 0
+	fence := ""
+	for i, line := range strings.SplitAfter(content, "\n") {
+		if f := fenceMarker(line); f != "" {
+			// A fence closes with a bare run of at least as many of the
+			// same character; anything else inside a block is content
+			rest := strings.TrimSpace(strings.TrimLeft(line, " ")[len(f):])
+			if fence == "" {
+				fence = f
+			} else if f[0] == fence[0] && len(f) >= len(fence) && rest == "" {
+				fence = ""
+			}
+		} else if fence == "" {
+			found = append(found, scanLine(line, offset, i+1)...)
+		}
+		offset += len(line)
+	}
+	return found
+}
+
+// fenceMarker returns the run of ``` or ~~~ opening a fenced code block
+// line, or "" if the line is not a fence.
+func fenceMarker(line string) string {
+	t := strings.TrimLeft(line, " ")
+	if len(line)-len(t) > 3 {
+		return ""
+	}
+	for _, c := range []string{"`", "~"} {
+		n := 0
+		for n < len(t) && t[n] == c[0] {
+			n++
+		}
+		if n >= 3 {
+			return t[:n]
+		}
+	}
+	return ""
+}
+
+func scanLine(line string, offset, lineNo int) []Link {
+	var found []Link
+	for i := 0; i < len(line); {
+		switch {
+		case line[i] == '`':
+			// Skip the code span up to the matching run of backticks
+			n := 1
+			for i+n < len(line) && line[i+n] == '`' {
+				n++
+			}
+			run := line[i : i+n]
+			if end := strings.Index(line[i+n:], run); end >= 0 {
+				i += n + end + n
+			} else {
+				i += n
+			}
+		case strings.HasPrefix(line[i:], "[["):
+			end := strings.Index(line[i+2:], "]]")
+			if end < 0 {
+				i += 2
+				continue
+			}
+			if l, ok := parseWiki(line[i+2:i+2+end], offset+i+2, lineNo); ok {
+				found = append(found, l)
+			}
+			i += 2 + end + 2
+		case line[i] == '[' && (i == 0 || line[i-1] != '!'):
+			l, next, ok := parseMarkdown(line, i, offset, lineNo)
+			if ok {
+				found = append(found, l)
+			}
+			i = next
+		default:
+			i++
+		}
+	}
+	return found
+}
+
+// parseWiki parses the inside of [[...]] starting at byte offset start.
+func parseWiki(inner string, start, lineNo int) (Link, bool) {
+	target, text, _ := strings.Cut(inner, "|")
+	target, anchor, _ := strings.Cut(target, "#")
+	if strings.TrimSpace(target) == "" || strings.ContainsAny(target, "[]\n") {
+		return Link{}, false
+	}
+	return Link{
+		Kind:   Wiki,
+		Target: target,
+		Anchor: anchor,
+		Text:   text,
+		Line:   lineNo,
+		Start:  start,
+		End:    start + len(target),
+	}, true
+}
+
+// parseMarkdown parses [text](target) with '[' at line[i]. It returns the
+// index to continue scanning from.
+func parseMarkdown(line string, i, offset, lineNo int) (Link, int, bool) {
+	depth, j := 0, i
+	for ; j < len(line); j++ {
+		if line[j] == '[' {
+			depth++
+		} else if line[j] == ']' {
+			depth--
+			if depth == 0 {
+				break
+			}
+		}
+	}
+	if j >= len(line)-1 || line[j+1] != '(' {
+		return Link{}, i + 1, false
+	}
+	text := line[i+1 : j]
+
+	start := j + 2
+	end := strings.IndexByte(line[start:], ')')
+	if end < 0 {
+		return Link{}, i + 1, false
+	}
+	dest := line[start : start+end]
+	next := start + end + 1
+
+	// Drop an optional title: [text](target "title")
+	if sp := strings.IndexAny(dest, " \t"); sp >= 0 {
+		dest = dest[:sp]
+	}
+	target, anchor, _ := strings.Cut(dest, "#")
+	if target == "" || isExternal(target) {
+		return Link{}, next, false
+	}
+	return Link{
+		Kind:   Markdown,
+		Target: target,
+		Anchor: anchor,
+		Text:   text,
+		Line:   lineNo,
+		Start:  offset + start,
+		End:    offset + start + len(target),
+	}, next, true
+}
+
+// isExternal reports whether a markdown link target points outside the
+// vault (http:, mailto:, ...).
+func isExternal(target string) bool {
+	if strings.HasPrefix(target, "//") {
+		return true
+	}
+	if i := strings.IndexByte(target, ':'); i > 0 {
+		return !strings.ContainsAny(target[:i], "/.")
+	}
+	return false
+}
+
+// Resolve returns the note ID (with .md) a link from the note fromID points
+// to. Markdown links are relative to the linking note's folder unless they
+// start with '/'. Wikilinks name a note by its path from the vault root,
+// with or without .md; a bare name may also refer to a note in any folder,
+// see Resolver. ok is false for links to non-note files.
+func Resolve(fromID string, l Link) (id string, ok bool) {
+	target := l.Target
+	if l.Kind == Markdown {
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+		if strings.HasPrefix(target, "/") {
+			target = path.Clean(strings.TrimPrefix(target, "/"))
+		} else {
+			target = path.Join(path.Dir(fromID), target)
+		}
+		if strings.HasPrefix(target, "../") || target == ".." {
+			return "", false
+		}
+	} else {
+		target = strings.Trim(strings.TrimSpace(target), "/")
+	}
+
+	switch ext := path.Ext(target); ext {
+	case ".md":
+	case "":
+		target += ".md"
+	default:
+		if l.Kind == Markdown {
+			return "", false // an image or attachment
+		}
+		target += ".md" // [[v1.2 notes]]
+	}
+	return target, true
+}
+
+// Resolver resolves links against the notes that exist in a vault, so a
+// bare [[name]] finds its note in whichever folder it lives.
+type Resolver struct {
+	ids     map[string]string   // lower-case ID -> ID
+	byName  map[string][]string // lower-case file name -> IDs
+	byTitle map[string][]string // lower-case title -> IDs
+}
+
+// NewResolver returns a Resolver for a vault holding the notes ids.
+func NewResolver(ids []string) *Resolver {
+	r := &Resolver{ids: make(map[string]string), byName: make(map[string][]string), byTitle: make(map[string][]string)}
+	for _, id := range ids {
+		r.ids[strings.ToLower(id)] = id
+		name := strings.ToLower(path.Base(id))
+		r.byName[name] = append(r.byName[name], id)
+	}
+	for _, ids := range r.byName {
+		sort.Slice(ids, func(i, j int) bool {
+			if di, dj := strings.Count(ids[i], "/"), strings.Count(ids[j], "/"); di != dj {
+				return di < dj
+			}
+			return ids[i] < ids[j]
+		})
+	}
+	return r
+}
+
+// AddTitle lets bare wikilinks find the note id by its title, as in
+// [[Go Basics]] for go/basics.md. File names take precedence over titles.
+func (r *Resolver) AddTitle(id, title string) {
+	if title == "" {
+		return
+	}
+	key := strings.ToLower(title)
+	r.byTitle[key] = append(r.byTitle[key], id)
+	sort.Strings(r.byTitle[key])
+}
+
+// Resolve returns the note a link from fromID points to. exists reports
+// whether that note is in the vault; if not, id is where the link expects
+// it (a broken link). ok is false for links to non-note files.
+//
+// A bare wikilink that does not name a note at the vault root picks the
+// note of that name in the linking note's folder, or else the one with
+// the shortest path, or else a note with that title.
+func (r *Resolver) Resolve(fromID string, l Link) (id string, exists, ok bool) {
+	id, ok = Resolve(fromID, l)
+	if !ok {
+		return "", false, false
+	}
+	if existing, found := r.ids[strings.ToLower(id)]; found {
+		return existing, true, true
+	}
+	if l.Kind == Wiki && !strings.Contains(id, "/") {
+		candidates := r.byName[strings.ToLower(id)]
+		for _, c := range candidates {
+			if path.Dir(c) == path.Dir(fromID) {
+				return c, true, true
+			}
+		}
+		if len(candidates) > 0 {
+			return candidates[0], true, true
+		}
+		if titled := r.byTitle[strings.ToLower(strings.TrimSpace(l.Target))]; len(titled) > 0 {
+			return titled[0], true, true
+		}
+	}
+	return id, false, true
+}
+
+// refersTo reports whether link l in note fromID points to the note id.
+func (r *Resolver) refersTo(fromID string, l Link, id string) bool {
+	target, _, ok := r.Resolve(fromID, l)
+	return ok && strings.EqualFold(target, id)
+}
+
+// nameTaken reports whether a note other than except is called name.
+func (r *Resolver) nameTaken(name, except string) bool {
+	for _, id := range r.byName[strings.ToLower(name)] {
+		if !strings.EqualFold(id, except) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package links
+
+import (
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	content := "See [[Go Basics]] and [[go/channels#select|select]].\n" +
+		"Also [the guide](../guide.md#intro \"Guide\") and ![img](pic.png).\n" +
+		"External [site](https://example.com) and `[[not a link]]`.\n" +
+		"```\n[[inside fence]]\n```\n" +
+		"[[after fence]]"
+
+	got := Scan(content)
+	want := []struct {
+		kind           Kind
+		target, anchor string
+		line           int
+	}{
+		{Wiki, "Go Basics", "", 1},
+		{Wiki, "go/channels", "select", 1},
+		{Markdown, "../guide.md", "intro", 2},
+		{Wiki, "after fence", "", 7},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Scan found %d links, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		l := got[i]
+		if l.Kind != w.kind || l.Target != w.target || l.Anchor != w.anchor || l.Line != w.line {
+			t.Errorf("link %d = %+v, want %+v", i, l, w)
+		}
+		if content[l.Start:l.End] != l.Target {
+			t.Errorf("link %d offsets point at %q", i, content[l.Start:l.End])
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		from, target string
+		kind         Kind
+		want         string
+		ok           bool
+	}{
+		{"go/a.md", "b.md", Markdown, "go/b.md", true},
+		{"go/a.md", "../b", Markdown, "b.md", true},
+		{"go/a.md", "/rust/c.md", Markdown, "rust/c.md", true},
+		{"go/a.md", "my%20note.md", Markdown, "go/my note.md", true},
+		{"go/a.md", "pic.png", Markdown, "", false},
+		{"a.md", "../outside.md", Markdown, "", false},
+		{"go/a.md", "Go Basics", Wiki, "Go Basics.md", true},
+		{"go/a.md", "rust/c.md", Wiki, "rust/c.md", true},
+	}
+	for _, tt := range tests {
+		got, ok := Resolve(tt.from, Link{Kind: tt.kind, Target: tt.target})
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Resolve(%q, %q) = %q, %v; want %q, %v", tt.from, tt.target, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	content := "[[channels]], [[go/channels|alias]], [[go/channels.md#sel]], [[other]]\n" +
+		"[rel](channels.md) [rooted](/go/channels) [up](../go/channels.md#x)\n" +
+		"`[[channels]]`"
+
+	r := NewResolver([]string{"go/index.md", "go/channels.md", "other.md"})
+	got, n := Rewrite(content, "go/index.md", "go/channels.md", "concurrency/chan.md", r)
+	want := "[[chan]], [[concurrency/chan|alias]], [[concurrency/chan.md#sel]], [[other]]\n" +
+		"[rel](../concurrency/chan.md) [rooted](/concurrency/chan) [up](../concurrency/chan.md#x)\n" +
+		"`[[channels]]`"
+	if got != want || n != 6 {
+		t.Errorf("Rewrite = %d changes\n%s\nwant 6\n%s", n, got, want)
+	}
+
+	// ../go/channels.md from a top-level note escapes the vault: untouched
+	if _, n := Rewrite("[x](../go/channels.md)", "index.md", "go/channels.md", "b.md", r); n != 0 {
+		t.Errorf("expected no changes, got %d", n)
+	}
+
+	// A bare name is only rewritten where it meant the moved note, and
+	// becomes a path where the new name would be ambiguous
+	r = NewResolver([]string{"go/todo.md", "rust/todo.md", "go/a.md", "rust/b.md", "done.md"})
+	if got, _ := Rewrite("[[todo]]", "rust/b.md", "go/todo.md", "go/done.md", r); got != "[[todo]]" {
+		t.Errorf("rust/b.md's [[todo]] means rust/todo.md, got rewritten to %s", got)
+	}
+	if got, _ := Rewrite("[[todo]]", "go/a.md", "go/todo.md", "go/done.md", r); got != "[[go/done]]" {
+		t.Errorf("got %s, want [[go/done]]", got)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	r := NewResolver([]string{"Inbox.md", "go/todo.md", "rust/todo.md", "deep/er/todo.md"})
+	tests := []struct {
+		from, target string
+		want         string
+		exists       bool
+	}{
+		{"go/x.md", "todo", "go/todo.md", true},
+		{"rust/y.md", "todo", "rust/todo.md", true},
+		{"z.md", "todo", "go/todo.md", true},
+		{"z.md", "inbox", "Inbox.md", true},
+		{"z.md", "deep/er/todo", "deep/er/todo.md", true},
+		{"z.md", "missing", "missing.md", false},
+	}
+	for _, tt := range tests {
+		got, exists, ok := r.Resolve(tt.from, Link{Kind: Wiki, Target: tt.target})
+		if got != tt.want || exists != tt.exists || !ok {
+			t.Errorf("Resolve(%q, [[%s]]) = %q, %v, %v; want %q, %v", tt.from, tt.target, got, exists, ok, tt.want, tt.exists)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	content := "[sibling](b.md) [root](../top.md) [self](a.md#x) [[a]] [[b]] [file](spec.pdf)"
+	r := NewResolver([]string{"go/a.md", "go/b.md", "top.md"})
+	got, n := Move(content, "go/a.md", "rust/deep/a2.md", r)
+	want := "[sibling](../../go/b.md) [root](../../top.md) [self](a2.md#x) [[a2]] [[b]] [file](../../go/spec.pdf)"
+	if got != want || n != 5 {
+		t.Errorf("Move = %d changes\n%s\nwant 5\n%s", n, got, want)
+	}
+}
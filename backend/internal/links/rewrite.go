@@ -0,0 +1,117 @@
+package links
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Rewrite updates the links in content, the body of note fromID, that
+// point to oldID so they point to newID instead. r must know the vault as it
+// was before the move. Each link keeps its style: wikilinks stay bare names
+// (unless the new name is ambiguous) or vault paths, markdown links stay
+// relative (or rooted), and targets written without .md stay without it.
+// It returns the new content and the number of links changed.
+func Rewrite(content, fromID, oldID, newID string, r *Resolver) (string, int) {
+	return replace(content, func(l Link) (string, bool) {
+		if !r.refersTo(fromID, l, oldID) {
+			return "", false
+		}
+		return retarget(l, fromID, newID, r.nameTaken(path.Base(newID), oldID)), true
+	})
+}
+
+// Move updates the links in content after its own note moved from oldID
+// to newID: relative markdown links are recomputed from the new folder and
+// links to the note itself follow it. r must know the vault as it was
+// before the move.
+func Move(content, oldID, newID string, r *Resolver) (string, int) {
+	return replace(content, func(l Link) (string, bool) {
+		if r.refersTo(oldID, l, oldID) {
+			return retarget(l, newID, newID, r.nameTaken(path.Base(newID), oldID)), true
+		}
+		if l.Kind != Markdown || strings.HasPrefix(l.Target, "/") || path.Dir(oldID) == path.Dir(newID) {
+			return "", false
+		}
+		target, ok := Resolve(oldID, l)
+		if !ok {
+			// Attachments move relative to the note just the same
+			target = path.Join(path.Dir(oldID), l.Target)
+			if strings.HasPrefix(target, "../") {
+				return "", false
+			}
+			return relPath(path.Dir(newID), target), true
+		}
+		return retarget(l, newID, target, false), true
+	})
+}
+
+// retarget renders a link target pointing from note fromID to note id in
+// the style of l. A bare wikilink becomes a vault path if fullPath is set.
+func retarget(l Link, fromID, id string, fullPath bool) string {
+	keepExt := strings.HasSuffix(strings.ToLower(l.Target), ".md")
+	if !keepExt {
+		id = strings.TrimSuffix(id, ".md")
+	}
+
+	if l.Kind == Wiki {
+		if !fullPath && !strings.Contains(strings.Trim(l.Target, "/"), "/") {
+			return path.Base(id)
+		}
+		return id
+	}
+
+	var target string
+	if strings.HasPrefix(l.Target, "/") {
+		target = "/" + id
+	} else {
+		target = relPath(path.Dir(fromID), id)
+	}
+	if strings.Contains(l.Target, "%20") || strings.Contains(target, " ") && !strings.Contains(l.Target, " ") {
+		target = strings.ReplaceAll(target, " ", "%20")
+	}
+	return target
+}
+
+// relPath returns the slash path to target relative to the folder dir
+// ("." for the vault root).
+func relPath(dir, target string) string {
+	if dir == "." {
+		return target
+	}
+	from := strings.Split(dir, "/")
+	to := strings.Split(target, "/")
+	i := 0
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+	return strings.Repeat("../", len(from)-i) + strings.Join(to[i:], "/")
+}
+
+// replace rewrites the targets of the links fn returns a new target for.
+func replace(content string, fn func(Link) (string, bool)) (string, int) {
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	for _, l := range Scan(content) {
+		if target, ok := fn(l); ok && target != l.Target {
+			edits = append(edits, edit{l.Start, l.End, target})
+		}
+	}
+	if len(edits) == 0 {
+		return content, 0
+	}
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var sb strings.Builder
+	last := 0
+	for _, e := range edits {
+		sb.WriteString(content[last:e.start])
+		sb.WriteString(e.text)
+		last = e.end
+	}
+	sb.WriteString(content[last:])
+	return sb.String(), len(edits)
+}
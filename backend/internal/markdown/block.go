@@ -0,0 +1,591 @@
+// Package markdown renders notes to HTML: CommonMark block and inline
+// syntax plus the GitHub extensions notes commonly use (tables, task lists,
+// strikethrough, footnotes, bare URL autolinks) and [[wikilinks]].
+//
+// The output is safe to embed in a page: text is always escaped, raw HTML
+// is limited to a few attribute-free formatting tags, and link and image
+// URLs are restricted to harmless schemes.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+type nodeKind int
+
+const (
+	paragraph nodeKind = iota
+	heading
+	codeBlock
+	quote
+	list
+	listItem
+	thematicBreak
+	table
+)
+
+type node struct {
+	kind     nodeKind
+	text     string // paragraph and heading inline source, code block content
+	level    int    // heading level
+	info     string // code block info string
+	fenced   bool   // code block
+	children []*node
+
+	ordered bool // list
+	start   int
+	tight   bool
+
+	task int // list item: 0 no checkbox, 1 unchecked, 2 checked
+	line int // heading, list item, fenced code: 0-based source line where it starts
+
+	align []string   // table column alignment: "", "left", "center", "right"
+	rows  [][]string // table rows, the header first
+}
+
+const (
+	taskNone = iota
+	taskOpen
+	taskDone
+)
+
+type linkRef struct {
+	dest, title string
+}
+
+// parser holds document-wide state collected while parsing blocks:
+// link reference definitions and footnote definitions.
+type parser struct {
+	refs      map[string]linkRef
+	footnotes map[string][]*node
+}
+
+func newParser() *parser {
+	return &parser{refs: make(map[string]linkRef), footnotes: make(map[string][]*node)}
+}
+
+// parse splits src into lines and parses the block structure.
+func (p *parser) parse(src string) []*node {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(src, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = expandTabs(l)
+	}
+	return p.parseBlocks(lines, 0)
+}
+
+// expandTabs replaces tabs in a line's leading whitespace with spaces up to
+// the next multiple of four columns.
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var sb strings.Builder
+	col := 0
+	for i, c := range line {
+		switch c {
+		case '\t':
+			n := 4 - col%4
+			sb.WriteString(strings.Repeat(" ", n))
+			col += n
+		case ' ':
+			sb.WriteByte(' ')
+			col++
+		default:
+			sb.WriteString(line[i:])
+			return sb.String()
+		}
+	}
+	return sb.String()
+}
+
+func isBlank(line string) bool { return strings.TrimSpace(line) == "" }
+
+func indentOf(line string) int { return len(line) - len(strings.TrimLeft(line, " ")) }
+
+var (
+	atxRe      = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	hrRe       = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	setextRe   = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	fenceRe    = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*(.*)$")
+	refDefRe   = regexp.MustCompile(`^ {0,3}\[((?:[^\]\\]|\\.)+)\]:[ \t]*(<[^<>\n]*>|\S+)(?:[ \t]+("[^"]*"|'[^']*'|\([^()]*\)))?[ \t]*$`)
+	footnoteRe = regexp.MustCompile(`^ {0,3}\[\^([^\]\s]+)\]:[ \t]?(.*)$`)
+	tableSepRe = regexp.MustCompile(`^ {0,3}\|?(?:[ \t]*:?-+:?[ \t]*\|)*[ \t]*:?-+:?[ \t]*\|?[ \t]*$`)
+	orderedRe  = regexp.MustCompile(`^( {0,3})(\d{1,9})([.)])( +|$)`)
+	bulletRe   = regexp.MustCompile(`^( {0,3})([-+*])( +|$)`)
+	taskRe     = regexp.MustCompile(`^\[([ xX])\](?:[ \t]+|$)`)
+)
+
+// marker describes a list item marker at the start of a line.
+type marker struct {
+	ordered bool
+	char    byte // bullet character or ordered delimiter
+	start   int
+	indent  int // column where the item's content starts
+	rest    string
+}
+
+func listMarker(line string) (marker, bool) {
+	var m marker
+	var groups []string
+	if groups = bulletRe.FindStringSubmatch(line); groups != nil {
+		m.char = groups[2][0]
+	} else if groups = orderedRe.FindStringSubmatch(line); groups != nil {
+		m.ordered = true
+		m.char = groups[3][0]
+		m.start = atoi(groups[2])
+		groups = []string{groups[0], groups[1], groups[2] + groups[3], groups[4]}
+	} else {
+		return m, false
+	}
+
+	markerEnd := len(groups[1]) + len(groups[2])
+	spaces := len(groups[3])
+	switch {
+	case spaces == 0:
+		// Empty item: content starts one column after the marker
+		m.indent = markerEnd + 1
+		m.rest = ""
+	case spaces > 4:
+		// Indented code inside the item: only one space belongs to the marker
+		m.indent = markerEnd + 1
+		m.rest = line[m.indent:]
+	default:
+		m.indent = markerEnd + spaces
+		m.rest = line[m.indent:]
+	}
+	return m, true
+}
+
+func atoi(s string) int {
+	n := 0
+	for _, c := range s {
+		n = n*10 + int(c-'0')
+	}
+	return n
+}
+
+// interrupts reports whether line starts a block that ends a paragraph.
+func (p *parser) interrupts(line string) bool {
+	if atxRe.MatchString(line) || hrRe.MatchString(line) || fenceRe.MatchString(line) {
+		return true
+	}
+	if _, ok := quotePrefix(line); ok {
+		return true
+	}
+	if m, ok := listMarker(line); ok {
+		// Only non-empty bullets and lists starting at 1 interrupt a paragraph
+		return !isBlank(m.rest) && (!m.ordered || m.start == 1)
+	}
+	return false
+}
+
+func quotePrefix(line string) (string, bool) {
+	if indentOf(line) > 3 {
+		return "", false
+	}
+	t := strings.TrimLeft(line, " ")
+	if !strings.HasPrefix(t, ">") {
+		return "", false
+	}
+	t = t[1:]
+	if strings.HasPrefix(t, " ") {
+		t = t[1:]
+	}
+	return t, true
+}
+
+// parseBlocks parses lines into blocks. offset is the source line number of
+// lines[0]; container blocks keep one entry per source line so that nested
+// blocks can be located too.
+func (p *parser) parseBlocks(lines []string, offset int) []*node {
+	var out []*node
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+		case fenceRe.MatchString(line):
+			n, next := p.fenced(lines, i, offset)
+			out = append(out, n)
+			i = next
+		case atxRe.MatchString(line):
+			m := atxRe.FindStringSubmatch(line)
+			text := m[2]
+			if strings.Trim(text, "#") == "" && strings.TrimSpace(text) != "" {
+				text = "" // "### ###"
+			}
+			out = append(out, &node{kind: heading, level: len(m[1]), text: strings.TrimSpace(text), line: offset + i})
+			i++
+		case hrRe.MatchString(line):
+			out = append(out, &node{kind: thematicBreak})
+			i++
+		case indentOf(line) >= 4:
+			n, next := indentedCode(lines, i)
+			out = append(out, n)
+			i = next
+		default:
+			if _, ok := quotePrefix(line); ok {
+				n, next := p.blockquote(lines, i, offset)
+				out = append(out, n)
+				i = next
+			} else if _, ok := listMarker(line); ok {
+				n, next := p.list(lines, i, offset)
+				out = append(out, n)
+				i = next
+			} else if n, next, ok := p.table(lines, i); ok {
+				out = append(out, n)
+				i = next
+			} else if next, ok := p.footnote(lines, i, offset); ok {
+				i = next
+			} else {
+				n, next := p.paragraph(lines, i, offset)
+				if n != nil {
+					out = append(out, n)
+				}
+				i = next
+			}
+		}
+	}
+	return out
+}
+
+func (p *parser) fenced(lines []string, i, offset int) (*node, int) {
+	m := fenceRe.FindStringSubmatch(lines[i])
+	indent, fence, info := len(m[1]), m[2], strings.TrimSpace(m[3])
+	if fence[0] == '`' && strings.Contains(info, "`") {
+		// Not a fence after all: an inline code span
+		return p.paragraph(lines, i, offset)
+	}
+
+	var body []string
+	j := i + 1
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		t := strings.TrimLeft(l, " ")
+		if indentOf(l) <= 3 && strings.HasPrefix(t, fence) && strings.Trim(t, string(fence[0])+" \t") == "" {
+			j++
+			break
+		}
+		// Remove up to the opening fence's indentation
+		strip := indentOf(l)
+		if strip > indent {
+			strip = indent
+		}
+		body = append(body, l[strip:])
+	}
+	text := strings.Join(body, "\n")
+	if len(body) > 0 {
+		text += "\n"
+	}
+	return &node{kind: codeBlock, text: text, info: info, fenced: true, line: offset + i}, j
+}
+
+func indentedCode(lines []string, i int) (*node, int) {
+	var body []string
+	j := i
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		if isBlank(l) {
+			body = append(body, strings.TrimPrefix(l, "    "))
+			continue
+		}
+		if indentOf(l) < 4 {
+			break
+		}
+		body = append(body, l[4:])
+	}
+	// Trailing blank lines are not part of the block
+	for len(body) > 0 && isBlank(body[len(body)-1]) {
+		body = body[:len(body)-1]
+		j--
+	}
+	return &node{kind: codeBlock, text: strings.Join(body, "\n") + "\n"}, j
+}
+
+func (p *parser) blockquote(lines []string, i, offset int) (*node, int) {
+	var inner []string
+	j := i
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		if rest, ok := quotePrefix(l); ok {
+			inner = append(inner, rest)
+			continue
+		}
+		if isBlank(l) {
+			break
+		}
+		// Lazy continuation of a paragraph inside the quote
+		if len(inner) > 0 && !isBlank(inner[len(inner)-1]) && !p.interrupts(l) && indentOf(l) < 4 {
+			inner = append(inner, l)
+			continue
+		}
+		break
+	}
+	return &node{kind: quote, children: p.parseBlocks(inner, offset+i)}, j
+}
+
+func (p *parser) list(lines []string, i, offset int) (*node, int) {
+	first, _ := listMarker(lines[i])
+	l := &node{kind: list, ordered: first.ordered, start: first.start, tight: true}
+
+	j := i
+	for j < len(lines) {
+		m, ok := listMarker(lines[j])
+		if !ok || m.ordered != first.ordered || m.char != first.char || hrRe.MatchString(lines[j]) {
+			break
+		}
+		itemStart := j
+		item := []string{m.rest}
+		j++
+		for j < len(lines) {
+			line := lines[j]
+			if isBlank(line) {
+				if isBlank(m.rest) && len(item) == 1 {
+					break // an item can start with at most one blank line
+				}
+				item = append(item, "")
+				j++
+				continue
+			}
+			if indentOf(line) >= m.indent {
+				item = append(item, line[m.indent:])
+				j++
+				continue
+			}
+			// Lazy continuation of the item's last paragraph
+			if last := item[len(item)-1]; !isBlank(last) && !p.interrupts(line) && !fenceRe.MatchString(last) {
+				if _, isItem := listMarker(line); !isItem {
+					item = append(item, line)
+					j++
+					continue
+				}
+			}
+			break
+		}
+
+		// Blank lines at the end separate items; between items they make
+		// the list loose
+		trailing := 0
+		for len(item) > 1 && isBlank(item[len(item)-1]) {
+			item = item[:len(item)-1]
+			trailing++
+		}
+		if trailing > 0 && j < len(lines) {
+			if next, ok := listMarker(lines[j]); ok && next.ordered == first.ordered && next.char == first.char {
+				l.tight = false
+			}
+		}
+
+		li := &node{kind: listItem, line: offset + itemStart}
+		if tm := taskRe.FindStringSubmatch(item[0]); tm != nil {
+			li.task = taskOpen
+			if tm[1] != " " {
+				li.task = taskDone
+			}
+			item[0] = item[0][len(tm[0]):]
+		}
+		li.children = p.parseBlocks(item, offset+itemStart)
+		if len(li.children) > 1 && hasInnerBlank(item) {
+			l.tight = false
+		}
+		l.children = append(l.children, li)
+
+		if trailing > 0 {
+			if _, ok := listMarker(safeLine(lines, j)); !ok {
+				break
+			}
+		}
+	}
+	return l, j
+}
+
+func safeLine(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return ""
+}
+
+// hasInnerBlank reports whether a blank line separates two blocks of an
+// item, ignoring blank lines inside fenced code.
+func hasInnerBlank(item []string) bool {
+	fence := ""
+	for _, l := range item {
+		if m := fenceRe.FindStringSubmatch(l); m != nil {
+			if fence == "" {
+				fence = m[2]
+			} else if strings.HasPrefix(m[2], fence[:1]) {
+				fence = ""
+			}
+			continue
+		}
+		if fence == "" && isBlank(l) {
+			return true
+		}
+	}
+	return false
+}
+
+// table parses a GFM table: a header row, a delimiter row with the same
+// number of cells, and body rows up to the next blank line or block.
+func (p *parser) table(lines []string, i int) (*node, int, bool) {
+	if i+1 >= len(lines) || !strings.Contains(lines[i], "|") || !tableSepRe.MatchString(lines[i+1]) {
+		return nil, i, false
+	}
+	header := splitRow(lines[i])
+	seps := splitRow(lines[i+1])
+	if len(header) != len(seps) {
+		return nil, i, false
+	}
+
+	t := &node{kind: table, rows: [][]string{header}}
+	for _, s := range seps {
+		s = strings.TrimSpace(s)
+		left, right := strings.HasPrefix(s, ":"), strings.HasSuffix(s, ":")
+		switch {
+		case left && right:
+			t.align = append(t.align, "center")
+		case left:
+			t.align = append(t.align, "left")
+		case right:
+			t.align = append(t.align, "right")
+		default:
+			t.align = append(t.align, "")
+		}
+	}
+
+	j := i + 2
+	for ; j < len(lines) && !isBlank(lines[j]) && !p.interrupts(lines[j]); j++ {
+		row := splitRow(lines[j])
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		t.rows = append(t.rows, row[:len(header)])
+	}
+	return t, j, true
+}
+
+// splitRow splits a table row on pipes that are not escaped or inside a
+// code span.
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	inCode := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case c == '`':
+			inCode = !inCode
+			cell.WriteByte(c)
+		case c == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(c)
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// footnote parses a footnote definition "[^label]: text" and its indented
+// continuation lines.
+func (p *parser) footnote(lines []string, i, offset int) (int, bool) {
+	m := footnoteRe.FindStringSubmatch(lines[i])
+	if m == nil {
+		return i, false
+	}
+	body := []string{m[2]}
+	j := i + 1
+scan:
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		switch {
+		case isBlank(l):
+			body = append(body, "")
+		case indentOf(l) >= 4:
+			body = append(body, l[4:])
+		case !isBlank(body[len(body)-1]) && !p.interrupts(l) && !footnoteRe.MatchString(l):
+			body = append(body, l)
+		default:
+			break scan
+		}
+	}
+	for len(body) > 1 && isBlank(body[len(body)-1]) {
+		body = body[:len(body)-1]
+	}
+	label := normalizeLabel(m[1])
+	if _, dup := p.footnotes[label]; !dup {
+		p.footnotes[label] = p.parseBlocks(body, offset+i)
+	}
+	return j, true
+}
+
+// paragraph collects paragraph lines, turning leading link reference
+// definitions into refs and a trailing setext underline into a heading.
+func (p *parser) paragraph(lines []string, i, offset int) (*node, int) {
+	var text []string
+	first := i
+	j := i
+	for ; j < len(lines); j++ {
+		l := lines[j]
+		if isBlank(l) {
+			break
+		}
+		if len(text) > 0 {
+			if m := setextRe.FindStringSubmatch(l); m != nil {
+				level := 1
+				if m[1][0] == '-' {
+					level = 2
+				}
+				return &node{kind: heading, level: level, text: strings.TrimSpace(strings.Join(text, "\n")), line: offset + first}, j + 1
+			}
+			if p.interrupts(l) {
+				break
+			}
+		}
+		if len(text) == 0 {
+			if m := refDefRe.FindStringSubmatch(l); m != nil {
+				p.addRef(m)
+				first = j + 1
+				continue
+			}
+		}
+		text = append(text, strings.TrimLeft(l, " "))
+	}
+	if len(text) == 0 {
+		return nil, j
+	}
+	return &node{kind: paragraph, text: strings.TrimRight(strings.Join(text, "\n"), " ")}, j
+}
+
+func (p *parser) addRef(m []string) {
+	label := normalizeLabel(m[1])
+	if _, dup := p.refs[label]; dup || label == "" {
+		return
+	}
+	dest := m[2]
+	if strings.HasPrefix(dest, "<") {
+		dest = dest[1 : len(dest)-1]
+	}
+	title := m[3]
+	if len(title) >= 2 {
+		title = title[1 : len(title)-1]
+	}
+	p.refs[label] = linkRef{dest: unescape(dest), title: unescape(title)}
+}
+
+// normalizeLabel folds case and whitespace of a link label.
+func normalizeLabel(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
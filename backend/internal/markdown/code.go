@@ -0,0 +1,49 @@
+package markdown
+
+import "strings"
+
+// CodeBlock is a fenced code block.
+type CodeBlock struct {
+	Line    int    // 1-based line of src with the opening fence
+	Lang    string // first word of the info string, lower-case
+	Code    string
+	Heading string // text of the closest heading above the block, if any
+	Anchor  string // that heading's anchor
+}
+
+// CodeBlocks returns the fenced code blocks of src in document order,
+// including those nested in lists and blockquotes.
+func CodeBlocks(src string) []CodeBlock {
+	p := newParser()
+	blocks := p.parse(src)
+	r := &renderer{p: p, slugs: make(map[string]bool), fnNum: make(map[string]int)}
+
+	var out []CodeBlock
+	var current Heading
+	var walk func(nodes []*node)
+	walk = func(nodes []*node) {
+		for _, n := range nodes {
+			switch {
+			case n.kind == heading:
+				// Anchors must be assigned in the same order as Render
+				text := plainText(r.inline(n.text))
+				current = Heading{Text: text, Anchor: r.headingID(text)}
+			case n.kind == codeBlock && n.fenced:
+				lang := ""
+				if fields := strings.Fields(n.info); len(fields) > 0 {
+					lang = strings.ToLower(fields[0])
+				}
+				out = append(out, CodeBlock{
+					Line:    n.line + 1,
+					Lang:    lang,
+					Code:    n.text,
+					Heading: current.Text,
+					Anchor:  current.Anchor,
+				})
+			}
+			walk(n.children)
+		}
+	}
+	walk(blocks)
+	return out
+}
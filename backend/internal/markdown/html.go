@@ -0,0 +1,245 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Render converts markdown to an HTML fragment. Headings get id attributes
+// from Slug, deduplicated with numeric suffixes, and referenced footnotes
+// are collected into a section at the end.
+func Render(src string) string {
+	p := newParser()
+	blocks := p.parse(src)
+
+	r := &renderer{p: p, slugs: make(map[string]bool), fnNum: make(map[string]int)}
+	r.blocks(blocks, false)
+	r.footnotes()
+	return r.sb.String()
+}
+
+type renderer struct {
+	p  *parser
+	sb strings.Builder
+
+	slugs   map[string]bool // heading ids used so far
+	fnOrder []string        // footnote labels in order of first reference
+	fnNum   map[string]int
+}
+
+func (r *renderer) blocks(nodes []*node, tight bool) {
+	for _, n := range nodes {
+		r.block(n, tight)
+	}
+}
+
+func (r *renderer) block(n *node, tight bool) {
+	sb := &r.sb
+	switch n.kind {
+	case paragraph:
+		if tight {
+			sb.WriteString(r.inline(n.text))
+			return
+		}
+		sb.WriteString("<p>" + r.inline(n.text) + "</p>\n")
+	case heading:
+		content := r.inline(n.text)
+		tag := "h" + strconv.Itoa(n.level)
+		sb.WriteString("<" + tag + ` id="` + escape(r.headingID(plainText(content))) + `">` + content + "</" + tag + ">\n")
+	case codeBlock:
+		sb.WriteString("<pre><code")
+		if lang := language(n.info); lang != "" {
+			sb.WriteString(` class="language-` + lang + `"`)
+		}
+		sb.WriteString(">" + escape(n.text) + "</code></pre>\n")
+	case quote:
+		sb.WriteString("<blockquote>\n")
+		r.blocks(n.children, false)
+		sb.WriteString("</blockquote>\n")
+	case list:
+		r.list(n)
+	case thematicBreak:
+		sb.WriteString("<hr />\n")
+	case table:
+		r.table(n)
+	}
+}
+
+func (r *renderer) list(n *node) {
+	sb := &r.sb
+	tag := "ul"
+	if n.ordered {
+		tag = "ol"
+	}
+	sb.WriteString("<" + tag)
+	if n.ordered && n.start != 1 {
+		sb.WriteString(` start="` + strconv.Itoa(n.start) + `"`)
+	}
+	for _, item := range n.children {
+		if item.task != taskNone {
+			sb.WriteString(` class="contains-task-list"`)
+			break
+		}
+	}
+	sb.WriteString(">\n")
+
+	for _, item := range n.children {
+		sb.WriteString("<li")
+		if item.task != taskNone {
+			sb.WriteString(` class="task-list-item"><input type="checkbox" disabled`)
+			if item.task == taskDone {
+				sb.WriteString(" checked")
+			}
+			sb.WriteString(" /> ")
+		} else {
+			sb.WriteString(">")
+		}
+
+		for _, child := range item.children {
+			if (!n.tight || child.kind != paragraph) && !strings.HasSuffix(sb.String(), "\n") {
+				sb.WriteString("\n")
+			}
+			r.block(child, n.tight)
+		}
+		sb.WriteString("</li>\n")
+	}
+	sb.WriteString("</" + tag + ">\n")
+}
+
+func (r *renderer) table(n *node) {
+	sb := &r.sb
+	cell := func(tag, align, text string) {
+		sb.WriteString("<" + tag)
+		if align != "" {
+			sb.WriteString(` align="` + align + `"`)
+		}
+		sb.WriteString(">" + r.inline(text) + "</" + tag + ">\n")
+	}
+
+	sb.WriteString("<table>\n<thead>\n<tr>\n")
+	for i, text := range n.rows[0] {
+		cell("th", n.align[i], text)
+	}
+	sb.WriteString("</tr>\n</thead>\n")
+	if len(n.rows) > 1 {
+		sb.WriteString("<tbody>\n")
+		for _, row := range n.rows[1:] {
+			sb.WriteString("<tr>\n")
+			for i, text := range row {
+				cell("td", n.align[i], text)
+			}
+			sb.WriteString("</tr>\n")
+		}
+		sb.WriteString("</tbody>\n")
+	}
+	sb.WriteString("</table>\n")
+}
+
+// footnotes renders the referenced footnotes in order of first reference.
+// Footnotes referenced only from other footnotes are numbered as they are
+// rendered.
+func (r *renderer) footnotes() {
+	if len(r.fnOrder) == 0 {
+		return
+	}
+	sb := &r.sb
+	sb.WriteString("<section class=\"footnotes\">\n<ol>\n")
+	for i := 0; i < len(r.fnOrder); i++ {
+		num := strconv.Itoa(i + 1)
+		sb.WriteString(`<li id="fn-` + num + `">` + "\n")
+		r.blocks(r.p.footnotes[r.fnOrder[i]], false)
+		sb.WriteString(`<a href="#fnref-` + num + `" class="footnote-backref">&#8617;</a>` + "\n</li>\n")
+	}
+	sb.WriteString("</ol>\n</section>\n")
+}
+
+// headingID returns a unique id for a heading with the given text.
+func (r *renderer) headingID(text string) string {
+	slug := Slug(text)
+	if slug == "" {
+		slug = "section"
+	}
+	id := slug
+	for n := 1; r.slugs[id]; n++ {
+		id = slug + "-" + strconv.Itoa(n)
+	}
+	r.slugs[id] = true
+	return id
+}
+
+// Slug turns heading text into an anchor the way GitHub does: lower case,
+// punctuation removed, spaces replaced by hyphens.
+func Slug(text string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '-' || c == '_':
+			sb.WriteRune(c)
+		case c == ' ':
+			sb.WriteByte('-')
+		}
+	}
+	return sb.String()
+}
+
+var tagsRe = regexp.MustCompile(`<[^>]*>`)
+
+// plainText strips tags from rendered inline HTML and decodes entities.
+func plainText(s string) string {
+	return html.UnescapeString(tagsRe.ReplaceAllString(s, ""))
+}
+
+var langRe = regexp.MustCompile(`^[A-Za-z0-9_+#.\-]+$`)
+
+// language returns the first word of a code block's info string when it
+// is safe to use as a class name.
+func language(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 || !langRe.MatchString(fields[0]) {
+		return ""
+	}
+	return fields[0]
+}
+
+// safeSchemes are the URL schemes links and images may use. Relative URLs
+// and fragments are always allowed.
+var safeSchemes = map[string]bool{
+	"http": true, "https": true, "mailto": true, "tel": true, "ftp": true,
+}
+
+// safeURL returns u, or "#" when u uses a scheme that could run script
+// (javascript:, data:, vbscript: and anything else not in safeSchemes).
+// Spaces and other characters unsafe in a URL are percent-encoded.
+func safeURL(u string) string {
+	u = strings.TrimSpace(u)
+	if i := strings.IndexAny(u, ":/?#"); i > 0 && u[i] == ':' {
+		// Browsers ignore control characters and whitespace in schemes
+		scheme := strings.Map(func(c rune) rune {
+			if c <= ' ' {
+				return -1
+			}
+			return unicode.ToLower(c)
+		}, u[:i])
+		if !safeSchemes[scheme] {
+			return "#"
+		}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(u); i++ {
+		c := u[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"<>\^`+"`{|}", c) >= 0 {
+			sb.WriteString("%" + strings.ToUpper(strconv.FormatInt(int64(c)|0x100, 16)[1:]))
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func escape(s string) string { return escaper.Replace(s) }
@@ -0,0 +1,677 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// inl is a node of the inline token list. Text tokens carry rendered
+// HTML; delimiter runs and bracket openers stay open until emphasis and
+// links are resolved.
+type inl struct {
+	prev, next *inl
+
+	html string
+
+	delim             byte // '*', '_' or '~' for a delimiter run
+	n, orig           int
+	canOpen, canClose bool
+
+	bracket bool // "[" or "![" waiting for its "]"
+	image   bool
+	active  bool
+	pos     int // source offset after the bracket
+
+	raw string // plain rendering of an autolink, used inside link text
+}
+
+type inlines struct {
+	head, tail *inl
+}
+
+func (l *inlines) push(t *inl) *inl {
+	t.prev = l.tail
+	if l.tail != nil {
+		l.tail.next = t
+	} else {
+		l.head = t
+	}
+	l.tail = t
+	return t
+}
+
+func (l *inlines) insertAfter(at, t *inl) {
+	t.prev, t.next = at, at.next
+	if at.next != nil {
+		at.next.prev = t
+	} else {
+		l.tail = t
+	}
+	at.next = t
+}
+
+func (l *inlines) insertBefore(at, t *inl) {
+	t.prev, t.next = at.prev, at
+	if at.prev != nil {
+		at.prev.next = t
+	} else {
+		l.head = t
+	}
+	at.prev = t
+}
+
+func (l *inlines) remove(t *inl) {
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else {
+		l.head = t.next
+	}
+	if t.next != nil {
+		t.next.prev = t.prev
+	} else {
+		l.tail = t.prev
+	}
+}
+
+func (t *inl) String() string {
+	if t.delim != 0 {
+		return strings.Repeat(string(t.delim), t.n)
+	}
+	return t.html
+}
+
+// allowedTags are the raw HTML tags passed through, and only without
+// attributes. Every other tag is escaped.
+var allowedTags = map[string]bool{
+	"b": true, "i": true, "u": true, "s": true, "em": true, "strong": true,
+	"del": true, "ins": true, "mark": true, "sub": true, "sup": true,
+	"kbd": true, "code": true, "small": true, "br": true,
+}
+
+var (
+	entityRe   = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	autolinkRe = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.\-]{1,31}:[^\s<>]*)>`)
+	emailRe    = regexp.MustCompile(`^<([A-Za-z0-9.!#$%&'*+/=?^_` + "`" + `{|}~\-]+@[A-Za-z0-9](?:[A-Za-z0-9\-]{0,61}[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9\-]{0,61}[A-Za-z0-9])?)*)>`)
+	tagRe      = regexp.MustCompile(`^<(/?)([A-Za-z][A-Za-z0-9]*)[ \t]*(/?)>`)
+	anyTagRe   = regexp.MustCompile(`^<(?:/?[A-Za-z][A-Za-z0-9\-]*(?:\s[^<>]*)?/?|!--[\s\S]*?--|\?[\s\S]*?\?|![A-Z]+[^>]*|!\[CDATA\[[\s\S]*?\]\])>`)
+	footRefRe  = regexp.MustCompile(`^\[\^([^\]\s]+)\]`)
+	bareURLRe  = regexp.MustCompile(`^(?:https?://|www\.)[^\s<]*`)
+
+	trailingEntityRe = regexp.MustCompile(`&[A-Za-z0-9]+;$`)
+)
+
+// inline renders the inline content of a paragraph, heading or table cell.
+func (r *renderer) inline(src string) string {
+	var l inlines
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			l.push(&inl{html: text.String()})
+			text.Reset()
+		}
+	}
+	var open []string // raw HTML tags left open
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			flush()
+			l.push(&inl{html: "<br />\n"})
+			i = skipSpaces(src, i+2)
+		case c == '\\' && i+1 < len(src) && isASCIIPunct(src[i+1]):
+			text.WriteString(escape(src[i+1 : i+2]))
+			i += 2
+		case c == '`':
+			n := runLen(src, i, '`')
+			if end := closingBackticks(src, i+n, n); end >= 0 {
+				flush()
+				l.push(&inl{html: "<code>" + escape(codeSpan(src[i+n:end])) + "</code>"})
+				i = end + n
+			} else {
+				text.WriteString(src[i : i+n])
+				i += n
+			}
+		case c == '*' || c == '_' || c == '~':
+			flush()
+			n := runLen(src, i, c)
+			l.push(delimiterRun(src, i, n))
+			i += n
+		case c == '!' && strings.HasPrefix(src[i:], "![") && !strings.HasPrefix(src[i:], "![["):
+			flush()
+			l.push(&inl{html: "![", bracket: true, image: true, active: true, pos: i + 2})
+			i += 2
+		case c == '[':
+			if html, n := r.wikilink(src[i:]); n > 0 {
+				flush()
+				l.push(&inl{html: html, raw: escape(src[i : i+n])})
+				i += n
+			} else if html, n := r.footnoteRef(src[i:]); n > 0 {
+				flush()
+				l.push(&inl{html: html})
+				i += n
+			} else {
+				flush()
+				l.push(&inl{html: "[", bracket: true, active: true, pos: i + 1})
+				i++
+			}
+		case c == ']':
+			flush()
+			i = r.closeBracket(&l, src, i)
+		case c == '<':
+			if m := autolinkRe.FindStringSubmatch(src[i:]); m != nil {
+				flush()
+				l.push(autolink(m[1], m[1]))
+				i += len(m[0])
+			} else if m := emailRe.FindStringSubmatch(src[i:]); m != nil {
+				flush()
+				l.push(autolink("mailto:"+m[1], m[1]))
+				i += len(m[0])
+			} else if m := tagRe.FindStringSubmatch(src[i:]); m != nil && allowedTags[strings.ToLower(m[2])] {
+				name := strings.ToLower(m[2])
+				switch {
+				case name == "br" || m[3] != "":
+					text.WriteString("<" + name + " />")
+				case m[1] == "":
+					text.WriteString("<" + name + ">")
+					open = append(open, name)
+				case len(open) > 0 && open[len(open)-1] == name:
+					text.WriteString("</" + name + ">")
+					open = open[:len(open)-1]
+				default:
+					text.WriteString(escape(m[0])) // unbalanced closing tag
+				}
+				i += len(m[0])
+			} else if m := anyTagRe.FindString(src[i:]); m != "" {
+				text.WriteString(escape(m))
+				i += len(m)
+			} else {
+				text.WriteString("&lt;")
+				i++
+			}
+		case c == '&':
+			if m := entityRe.FindString(src[i:]); m != "" {
+				text.WriteString(m)
+				i += len(m)
+			} else {
+				text.WriteString("&amp;")
+				i++
+			}
+		case c == '\n':
+			// Two or more trailing spaces make a hard line break
+			s := text.String()
+			trimmed := strings.TrimRight(s, " ")
+			text.Reset()
+			text.WriteString(trimmed)
+			flush()
+			if len(s)-len(trimmed) >= 2 {
+				l.push(&inl{html: "<br />\n"})
+			} else {
+				l.push(&inl{html: "\n"})
+			}
+			i = skipSpaces(src, i+1)
+		case (c == 'h' || c == 'w') && wordStart(src, i):
+			if href, shown, n := bareURL(src[i:]); n > 0 {
+				flush()
+				l.push(autolink(href, shown))
+				i += n
+				continue
+			}
+			text.WriteByte(c)
+			i++
+		default:
+			_, size := utf8.DecodeRuneInString(src[i:])
+			text.WriteString(escape(src[i : i+size]))
+			i += size
+		}
+	}
+	flush()
+
+	r.emphasis(&l, nil)
+	var sb strings.Builder
+	for t := l.head; t != nil; t = t.next {
+		sb.WriteString(t.String())
+	}
+	for k := len(open) - 1; k >= 0; k-- {
+		sb.WriteString("</" + open[k] + ">")
+	}
+	return sb.String()
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+func runLen(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// closingBackticks returns the offset of the next backtick run of exactly
+// n backticks at or after i, or -1.
+func closingBackticks(s string, i, n int) int {
+	for i < len(s) {
+		j := strings.IndexByte(s[i:], '`')
+		if j < 0 {
+			return -1
+		}
+		i += j
+		m := runLen(s, i, '`')
+		if m == n {
+			return i
+		}
+		i += m
+	}
+	return -1
+}
+
+// codeSpan normalizes code span content: line endings become spaces and
+// one surrounding space is stripped when present on both sides.
+func codeSpan(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) >= 2 && s[0] == ' ' && s[len(s)-1] == ' ' && strings.Trim(s, " ") != "" {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
+
+// delimiterRun classifies a run of n emphasis characters at s[i] by
+// whether it can open and/or close emphasis.
+func delimiterRun(s string, i, n int) *inl {
+	c := s[i]
+	before, after := ' ', ' '
+	if i > 0 {
+		before, _ = utf8.DecodeLastRuneInString(s[:i])
+	}
+	if i+n < len(s) {
+		after, _ = utf8.DecodeRuneInString(s[i+n:])
+	}
+	left := !unicode.IsSpace(after) && (!isPunct(after) || unicode.IsSpace(before) || isPunct(before))
+	right := !unicode.IsSpace(before) && (!isPunct(before) || unicode.IsSpace(after) || isPunct(after))
+
+	t := &inl{delim: c, n: n, orig: n, canOpen: left, canClose: right}
+	switch c {
+	case '_':
+		t.canOpen = left && (!right || isPunct(before))
+		t.canClose = right && (!left || isPunct(after))
+	case '~':
+		if n > 2 {
+			t.canOpen, t.canClose = false, false
+		}
+	}
+	return t
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+// emphasis matches delimiter runs after bottom into em, strong and del
+// elements, following the CommonMark delimiter algorithm.
+func (r *renderer) emphasis(l *inlines, bottom *inl) {
+	closer := l.head
+	if bottom != nil {
+		closer = bottom.next
+	}
+	for closer != nil {
+		if closer.delim == 0 || !closer.canClose {
+			closer = closer.next
+			continue
+		}
+
+		var opener *inl
+		for o := closer.prev; o != nil && o != bottom; o = o.prev {
+			if o.delim != closer.delim || !o.canOpen || o.n == 0 {
+				continue
+			}
+			if closer.delim == '~' {
+				if o.n != closer.n {
+					continue
+				}
+			} else if (o.canClose || closer.canOpen) && (o.orig+closer.orig)%3 == 0 && (o.orig%3 != 0 || closer.orig%3 != 0) {
+				continue
+			}
+			opener = o
+			break
+		}
+
+		if opener == nil {
+			next := closer.next
+			if !closer.canOpen {
+				closer.html, closer.delim = closer.String(), 0
+			}
+			closer = next
+			continue
+		}
+
+		use, tag := 1, "em"
+		switch {
+		case closer.delim == '~':
+			use, tag = closer.n, "del"
+		case opener.n >= 2 && closer.n >= 2:
+			use, tag = 2, "strong"
+		}
+		opener.n -= use
+		closer.n -= use
+		l.insertAfter(opener, &inl{html: "<" + tag + ">"})
+		l.insertBefore(closer, &inl{html: "</" + tag + ">"})
+
+		// Unmatched delimiters between the pair are plain text now
+		for t := opener.next; t != closer; t = t.next {
+			if t.delim != 0 {
+				t.html, t.delim = t.String(), 0
+			}
+		}
+		if opener.n == 0 {
+			l.remove(opener)
+		}
+		if closer.n == 0 {
+			next := closer.next
+			l.remove(closer)
+			closer = next
+		}
+	}
+}
+
+// closeBracket handles a "]" at src[i]: when it closes a bracket opener
+// followed by a link destination or a known reference, the tokens in
+// between become a link or image. It returns the offset to continue at.
+func (r *renderer) closeBracket(l *inlines, src string, i int) int {
+	var opener *inl
+	for t := l.tail; t != nil; t = t.prev {
+		if t.bracket {
+			opener = t
+			break
+		}
+	}
+	if opener == nil {
+		l.push(&inl{html: "]"})
+		return i + 1
+	}
+	if !opener.active {
+		opener.bracket = false
+		l.push(&inl{html: "]"})
+		return i + 1
+	}
+
+	dest, title, end, ok := inlineLink(src, i+1)
+	if !ok {
+		label, next := src[opener.pos:i], i+1
+		if m := refLabel(src, i+1); m >= 0 {
+			if m > i+3 {
+				label = src[i+2 : m-1]
+			}
+			next = m
+		}
+		if ref, found := r.p.refs[normalizeLabel(label)]; found {
+			dest, title, end, ok = ref.dest, ref.title, next, true
+		}
+	}
+	if !ok {
+		opener.bracket = false
+		l.push(&inl{html: "]"})
+		return i + 1
+	}
+
+	r.emphasis(l, opener)
+	var inner strings.Builder
+	for t := opener.next; t != nil; t = t.next {
+		if t.raw != "" && !opener.image {
+			inner.WriteString(t.raw) // no links inside links
+		} else {
+			inner.WriteString(t.String())
+		}
+	}
+
+	var html string
+	if opener.image {
+		html = `<img src="` + escape(safeURL(dest)) + `" alt="` + escape(plainText(inner.String())) + `"`
+		if title != "" {
+			html += ` title="` + escape(title) + `"`
+		}
+		html += " />"
+	} else {
+		html = `<a href="` + escape(safeURL(dest)) + `"`
+		if title != "" {
+			html += ` title="` + escape(title) + `"`
+		}
+		html += ">" + inner.String() + "</a>"
+	}
+
+	// Replace the opener and everything after it with the link
+	if opener.prev != nil {
+		opener.prev.next = nil
+		l.tail = opener.prev
+	} else {
+		l.head, l.tail = nil, nil
+	}
+	if opener.image {
+		l.push(&inl{html: html})
+	} else {
+		l.push(&inl{html: html, raw: escape(src[opener.pos-1 : end])})
+		for t := l.head; t != nil; t = t.next {
+			if t.bracket && !t.image {
+				t.active = false
+			}
+		}
+	}
+	return end
+}
+
+// refLabel returns the offset after a "[label]" or "[]" at src[i], or -1.
+func refLabel(src string, i int) int {
+	if i >= len(src) || src[i] != '[' {
+		return -1
+	}
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '[':
+			return -1
+		case ']':
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// inlineLink parses "(dest "title")" at src[i].
+func inlineLink(src string, i int) (dest, title string, end int, ok bool) {
+	if i >= len(src) || src[i] != '(' {
+		return "", "", 0, false
+	}
+	j := skipWhitespace(src, i+1)
+
+	if j < len(src) && src[j] == '<' {
+		k := strings.IndexAny(src[j+1:], ">\n")
+		if k < 0 || src[j+1+k] != '>' {
+			return "", "", 0, false
+		}
+		dest = src[j+1 : j+1+k]
+		j += k + 2
+	} else {
+		start, depth := j, 0
+	scan:
+		for ; j < len(src); j++ {
+			switch c := src[j]; {
+			case c == '\\' && j+1 < len(src):
+				j++
+			case c == '(':
+				depth++
+			case c == ')':
+				if depth == 0 {
+					break scan
+				}
+				depth--
+			case c <= ' ':
+				break scan
+			}
+		}
+		dest = src[start:j]
+	}
+
+	k := skipWhitespace(src, j)
+	if k > j && k < len(src) && strings.IndexByte("\"'(", src[k]) >= 0 {
+		closing := src[k]
+		if closing == '(' {
+			closing = ')'
+		}
+		e := k + 1
+		for e < len(src) && src[e] != closing {
+			if src[e] == '\\' {
+				e++
+			}
+			e++
+		}
+		if e >= len(src) {
+			return "", "", 0, false
+		}
+		title = src[k+1 : e]
+		k = skipWhitespace(src, e+1)
+	}
+	if k >= len(src) || src[k] != ')' {
+		return "", "", 0, false
+	}
+	return unescape(dest), unescape(title), k + 1, true
+}
+
+func skipWhitespace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// unescape removes backslash escapes of ASCII punctuation.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// wikilink renders a [[target#anchor|alias]] at the start of s as a link
+// to the note, returning the HTML and the length consumed.
+func (r *renderer) wikilink(s string) (string, int) {
+	if !strings.HasPrefix(s, "[[") {
+		return "", 0
+	}
+	end := strings.Index(s, "]]")
+	if end < 0 {
+		return "", 0
+	}
+	inner := s[2:end]
+	if strings.TrimSpace(inner) == "" || strings.ContainsAny(inner, "[]\n") {
+		return "", 0
+	}
+
+	target, alias, hasAlias := strings.Cut(inner, "|")
+	target, anchor, _ := strings.Cut(target, "#")
+	target = strings.TrimSpace(target)
+	label := strings.TrimSpace(inner)
+	if hasAlias {
+		label = strings.TrimSpace(alias)
+	}
+
+	href := target
+	if href != "" && !strings.HasSuffix(strings.ToLower(href), ".md") {
+		href += ".md"
+	}
+	if anchor = strings.TrimSpace(anchor); anchor != "" {
+		href += "#" + Slug(anchor)
+	}
+	return `<a class="wikilink" href="` + escape(safeURL(href)) + `">` + escape(label) + `</a>`, end + 2
+}
+
+// footnoteRef renders a [^label] reference to a defined footnote, numbering
+// footnotes in order of first reference.
+func (r *renderer) footnoteRef(s string) (string, int) {
+	m := footRefRe.FindStringSubmatch(s)
+	if m == nil {
+		return "", 0
+	}
+	label := normalizeLabel(m[1])
+	if _, ok := r.p.footnotes[label]; !ok {
+		return "", 0
+	}
+
+	n, seen := r.fnNum[label]
+	if !seen {
+		r.fnOrder = append(r.fnOrder, label)
+		n = len(r.fnOrder)
+		r.fnNum[label] = n
+	}
+	num := strconv.Itoa(n)
+	id := ""
+	if !seen {
+		id = ` id="fnref-` + num + `"`
+	}
+	return `<sup class="footnote-ref"><a href="#fn-` + num + `"` + id + `>` + num + `</a></sup>`, len(m[0])
+}
+
+func autolink(href, shown string) *inl {
+	return &inl{
+		html: `<a href="` + escape(safeURL(href)) + `">` + escape(shown) + `</a>`,
+		raw:  escape(shown),
+	}
+}
+
+// wordStart reports whether s[i] begins a word, where a bare URL may start.
+func wordStart(s string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev, _ := utf8.DecodeLastRuneInString(s[:i])
+	return unicode.IsSpace(prev) || strings.ContainsRune("(*_~", prev)
+}
+
+// bareURL matches a GFM extended autolink ("https://...", "www....") at
+// the start of s, without trailing punctuation.
+func bareURL(s string) (href, shown string, n int) {
+	m := bareURLRe.FindString(s)
+	if m == "" {
+		return "", "", 0
+	}
+	for {
+		trimmed := strings.TrimRight(m, "?!.,:*_~'\"")
+		if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, ")") > strings.Count(trimmed, "(") {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if e := trailingEntityRe.FindStringIndex(trimmed); e != nil {
+			trimmed = trimmed[:e[0]]
+		}
+		if trimmed == m {
+			break
+		}
+		m = trimmed
+	}
+	if m == "www." || strings.HasSuffix(m, "://") {
+		return "", "", 0
+	}
+	href = m
+	if strings.HasPrefix(m, "www.") {
+		href = "http://" + m
+	}
+	return href, m, len(m)
+}
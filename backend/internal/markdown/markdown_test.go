@@ -0,0 +1,181 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"heading", "# Hello *world*", `<h1 id="hello-world">Hello <em>world</em></h1>` + "\n"},
+		{"setext", "Title\n===", `<h1 id="title">Title</h1>` + "\n"},
+		{"duplicate headings", "## A\n## A", `<h2 id="a">A</h2>` + "\n" + `<h2 id="a-1">A</h2>` + "\n"},
+		{"emphasis", "**b** _i_ ***bi*** ~~s~~ a*b*c",
+			"<p><strong>b</strong> <em>i</em> <em><strong>bi</strong></em> <del>s</del> a<em>b</em>c</p>\n"},
+		{"intraword underscore", "snake_case_name", "<p>snake_case_name</p>\n"},
+		{"code span", "use `a < b` here", "<p>use <code>a &lt; b</code> here</p>\n"},
+		{"hard break", "one  \ntwo\\\nthree", "<p>one<br />\ntwo<br />\nthree</p>\n"},
+		{"link", `[x](/a.md "T")`, `<p><a href="/a.md" title="T">x</a></p>` + "\n"},
+		{"reference link", "[x][r]\n\n[r]: https://e.com", `<p><a href="https://e.com">x</a></p>` + "\n"},
+		{"image", "![an *x*](p.png)", `<p><img src="p.png" alt="an x" /></p>` + "\n"},
+		{"autolinks", "<https://a.io> and www.b.io.", `<p><a href="https://a.io">https://a.io</a> and <a href="http://www.b.io">www.b.io</a>.</p>` + "\n"},
+		{"wikilink", "[[Go Basics#Setup|basics]]", `<p><a class="wikilink" href="Go%20Basics.md#setup">basics</a></p>` + "\n"},
+		{"fenced code", "```go\nx := 1 < 2\n```", `<pre><code class="language-go">x := 1 &lt; 2` + "\n</code></pre>\n"},
+		{"indented code", "    code", "<pre><code>code\n</code></pre>\n"},
+		{"blockquote", "> a\nb", "<blockquote>\n<p>a\nb</p>\n</blockquote>\n"},
+		{"hr", "---", "<hr />\n"},
+		{"tight list", "- a\n- b\n  - c", "<ul>\n<li>a</li>\n<li>b\n<ul>\n<li>c</li>\n</ul>\n</li>\n</ul>\n"},
+		{"loose list", "1. a\n\n2. b", "<ol>\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ol>\n"},
+		{"ordered start", "3) c", "<ol start=\"3\">\n<li>c</li>\n</ol>\n"},
+		{"task list", "- [ ] open\n- [x] done",
+			`<ul class="contains-task-list">` + "\n" +
+				`<li class="task-list-item"><input type="checkbox" disabled /> open</li>` + "\n" +
+				`<li class="task-list-item"><input type="checkbox" disabled checked /> done</li>` + "\n</ul>\n"},
+		{"table", "| a | b |\n|:-:|---|\n| `x\\|y` | 2 |",
+			"<table>\n<thead>\n<tr>\n<th align=\"center\">a</th>\n<th>b</th>\n</tr>\n</thead>\n" +
+				"<tbody>\n<tr>\n<td align=\"center\"><code>x|y</code></td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"footnotes", "A[^n] B[^m]\n\n[^m]: M.\n[^n]: N.\n[^unused]: U.",
+			`<p>A<sup class="footnote-ref"><a href="#fn-1" id="fnref-1">1</a></sup> B<sup class="footnote-ref"><a href="#fn-2" id="fnref-2">2</a></sup></p>` + "\n" +
+				"<section class=\"footnotes\">\n<ol>\n" +
+				"<li id=\"fn-1\">\n<p>N.</p>\n<a href=\"#fnref-1\" class=\"footnote-backref\">&#8617;</a>\n</li>\n" +
+				"<li id=\"fn-2\">\n<p>M.</p>\n<a href=\"#fnref-2\" class=\"footnote-backref\">&#8617;</a>\n</li>\n" +
+				"</ol>\n</section>\n"},
+		{"entities", "AT&T &copy; &#169;", "<p>AT&amp;T &copy; &#169;</p>\n"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.src); got != tt.want {
+			t.Errorf("%s: Render(%q) =\n%s\nwant\n%s", tt.name, tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRender_Sanitizes(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{`<img src=x onerror="alert(1)">`, "<p>&lt;img src=x onerror=&quot;alert(1)&quot;&gt;</p>\n"},
+		{`<b onclick="x()">b</b>`, "<p>&lt;b onclick=&quot;x()&quot;&gt;b&lt;/b&gt;</p>\n"},
+		{"<kbd>Ctrl</kbd> <b>open", "<p><kbd>Ctrl</kbd> <b>open</b></p>\n"},
+		{"[x](javascript:alert(1))", `<p><a href="#">x</a></p>` + "\n"},
+		{"[x](JavaScript&#58;alert(1))", `<p><a href="JavaScript&amp;#58;alert(1)">x</a></p>` + "\n"},
+		{"![x](data:text/html;base64,PHNjcmlwdD4=)", `<p><img src="#" alt="x" /></p>` + "\n"},
+		{"<vbscript:msgbox>", `<p><a href="#">vbscript:msgbox</a></p>` + "\n"},
+		{`[x](/a "t\" onmouseover=\"y")`, `<p><a href="/a" title="t&quot; onmouseover=&quot;y">x</a></p>` + "\n"},
+		{"```js\" onclick=\"x\nbody\n```", "<pre><code>body\n</code></pre>\n"},
+		{"<!-- hidden -->", "<p>&lt;!-- hidden --&gt;</p>\n"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.src); got != tt.want {
+			t.Errorf("Render(%q) =\n%s\nwant\n%s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRender_NoNestedLinks(t *testing.T) {
+	got := Render("[see https://a.io and [[b]]](c.md)")
+	if strings.Count(got, "<a ") != 1 {
+		t.Errorf("expected a single link, got %s", got)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"Hello World":         "hello-world",
+		"  Go: Channels & co": "go-channels--co",
+		"Ünïcode_ok-1":        "ünïcode_ok-1",
+	}
+	for in, want := range tests {
+		if got := Slug(in); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestOutline(t *testing.T) {
+	src := "# Intro\n\ntext\n\n## Setup *fast*\n\n```\n# not a heading\n```\n\nNotes\n-----\n\n> ### Quoted\n\n## Setup fast\n"
+	want := []Heading{
+		{1, "Intro", "intro", 1},
+		{2, "Setup fast", "setup-fast", 5},
+		{2, "Notes", "notes", 11},
+		{3, "Quoted", "quoted", 14},
+		{2, "Setup fast", "setup-fast-1", 16},
+	}
+	got := Outline(src)
+	if len(got) != len(want) {
+		t.Fatalf("Outline = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("heading %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Anchors are the ids Render uses
+	html := Render(src)
+	for _, h := range got {
+		if !strings.Contains(html, `id="`+h.Anchor+`"`) {
+			t.Errorf("Render has no heading with id %q", h.Anchor)
+		}
+	}
+}
+
+func TestSection(t *testing.T) {
+	src := "# A\n\nintro\n\n## B\n\nb text\n\n### C\n\nc text\n\n## D\n\nd text\n"
+	tests := []struct {
+		anchor, want string
+	}{
+		{"b", "## B\n\nb text\n\n### C\n\nc text\n"},
+		{"c", "### C\n\nc text\n"},
+		{"d", "## D\n\nd text\n"},
+		{"a", src},
+	}
+	for _, tt := range tests {
+		_, got, ok := Section(src, tt.anchor)
+		if !ok || got != tt.want {
+			t.Errorf("Section(%q) = %q, %v; want %q", tt.anchor, got, ok, tt.want)
+		}
+	}
+	if _, _, ok := Section(src, "missing"); ok {
+		t.Error("Section found a missing anchor")
+	}
+}
+
+func TestTasks(t *testing.T) {
+	src := "# Todo\n\n- [ ] write *docs*\n- [x] ship\n  - [X] nested\n- plain item\n\n```\n- [ ] not a task\n```\n\n> 1. [ ] quoted\n>    continued\n"
+	want := []Task{
+		{3, "write *docs*", false},
+		{4, "ship", true},
+		{5, "nested", true},
+		{12, "quoted continued", false},
+	}
+	got := Tasks(src)
+	if len(got) != len(want) {
+		t.Fatalf("Tasks = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("task %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCodeBlocks(t *testing.T) {
+	src := "```sh\nmake\n```\n\n# Setup\n\n## Code Snippet\n\n```Go main\nfmt.Println(1)\n```\n\n    indented\n\n- item\n\n  ~~~\n  plain\n  ~~~\n"
+	want := []CodeBlock{
+		{1, "sh", "make\n", "", ""},
+		{9, "go", "fmt.Println(1)\n", "Code Snippet", "code-snippet"},
+		{17, "", "plain\n", "Code Snippet", "code-snippet"},
+	}
+	got := CodeBlocks(src)
+	if len(got) != len(want) {
+		t.Fatalf("CodeBlocks = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("block %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package markdown
+
+import "strings"
+
+// Heading is an entry of a document outline.
+type Heading struct {
+	Level  int
+	Text   string // plain text, markup removed
+	Anchor string // the id Render gives the heading
+	Line   int    // 1-based line of src where the heading starts
+}
+
+// Outline returns the headings of src in document order, including those
+// nested in lists and blockquotes. Anchors match the heading ids in the
+// output of Render.
+func Outline(src string) []Heading {
+	p := newParser()
+	blocks := p.parse(src)
+	r := &renderer{p: p, slugs: make(map[string]bool), fnNum: make(map[string]int)}
+
+	var out []Heading
+	var walk func(nodes []*node)
+	walk = func(nodes []*node) {
+		for _, n := range nodes {
+			if n.kind == heading {
+				text := plainText(r.inline(n.text))
+				out = append(out, Heading{
+					Level:  n.level,
+					Text:   text,
+					Anchor: r.headingID(text),
+					Line:   n.line + 1,
+				})
+			}
+			walk(n.children)
+		}
+	}
+	walk(blocks)
+	return out
+}
+
+// Section returns the heading of src with the given anchor and the
+// markdown from that heading up to the next heading of the same or a
+// higher level.
+func Section(src, anchor string) (Heading, string, bool) {
+	outline := Outline(src)
+	for i, h := range outline {
+		if h.Anchor != anchor {
+			continue
+		}
+		lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+		end := len(lines)
+		for _, next := range outline[i+1:] {
+			if next.Level <= h.Level {
+				end = next.Line - 1
+				break
+			}
+		}
+		return h, strings.TrimRight(strings.Join(lines[h.Line-1:end], "\n"), " \t\n") + "\n", true
+	}
+	return Heading{}, "", false
+}
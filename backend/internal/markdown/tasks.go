@@ -0,0 +1,32 @@
+package markdown
+
+import "strings"
+
+// Task is a task list item ("- [ ] text").
+type Task struct {
+	Line int    // 1-based line of src with the item's marker
+	Text string // the item's first paragraph as written, on one line
+	Done bool
+}
+
+// Tasks returns the task list items of src in document order, including
+// nested ones. Items inside code blocks are not tasks.
+func Tasks(src string) []Task {
+	p := newParser()
+	var out []Task
+	var walk func(nodes []*node)
+	walk = func(nodes []*node) {
+		for _, n := range nodes {
+			if n.kind == listItem && n.task != taskNone {
+				t := Task{Line: n.line + 1, Done: n.task == taskDone}
+				if len(n.children) > 0 && n.children[0].kind == paragraph {
+					t.Text = strings.Join(strings.Fields(n.children[0].text), " ")
+				}
+				out = append(out, t)
+			}
+			walk(n.children)
+		}
+	}
+	walk(p.parse(src))
+	return out
+}
@@ -0,0 +1,29 @@
+package models
+
+// Graph is the vault's note graph: notes and tags as nodes, links and tag
+// membership as edges.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a note (ID is the note ID) or a tag (ID is "tag:" plus the
+// lower-case tag). Degree counts the node's edges in the whole vault, also
+// when the graph is a neighbourhood.
+type GraphNode struct {
+	ID     string `json:"id"`
+	Label  string `json:"label"`
+	Type   string `json:"type"` // "note" or "tag"
+	Degree int    `json:"degree"`
+	// Orphan is set on notes that neither link nor are linked to
+	Orphan bool `json:"orphan,omitempty"`
+}
+
+// GraphEdge connects two nodes. Links from one note to another are merged
+// into one edge whose Weight is the number of links.
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"` // "link" or "tag"
+	Weight int    `json:"weight"`
+}
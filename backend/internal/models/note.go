@@ -9,12 +9,168 @@ type Note struct {
 	Content   string    `json:"content,omitempty"` // Content is omitted in list view
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+	Tags      []string  `json:"tags,omitempty"`
+	Author    string    `json:"author,omitempty"`
+
+	// Metadata holds frontmatter keys without a dedicated field, as parsed
+	// from YAML (strings, numbers, booleans, lists and nested maps)
+	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// Version identifies the exact file content the note was read from.
+	// Pass it back to conditional writes to detect concurrent edits.
+	Version string `json:"version,omitempty"`
+
+	// Links are the outgoing wikilinks and relative markdown links, in
+	// order of appearance. Only set when the full note is read.
+	Links []Link `json:"links,omitempty"`
+
+	// Outline lists the body's headings in order. Only set when the full
+	// note is read.
+	Outline []Heading `json:"outline,omitempty"`
+
+	// Tasks are the note's task list items. Only set when the full note
+	// is read.
+	Tasks []Task `json:"tasks,omitempty"`
+
+	// Snippets are the note's fenced code blocks, for the search index.
+	// Not part of the JSON: the code is already in Content.
+	Snippets []Snippet `json:"-"`
+
+	// HTML is the rendered, sanitized body. Only set when requested with
+	// GET /api/notes/{id}?html=true.
+	HTML string `json:"html,omitempty"`
+
+	// Populated by version-controlled backends only
+	LastChangedBy string     `json:"lastChangedBy,omitempty"`
+	LastChangedAt *time.Time `json:"lastChangedAt,omitempty"`
+	LastCommit    string     `json:"lastCommit,omitempty"`
+}
+
+// Commit is a version-control commit that touched a note
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	Date    time.Time `json:"date"`
+	Message string    `json:"message"`
+}
+
+// NoteInfo is the lightweight, file-level metadata of a note.
+// It can be obtained without reading or parsing the note content.
+type NoteInfo struct {
+	ID      string    `json:"id"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// Link is a link from one note to another. ID is the note it points to as
+// far as the link text alone tells; the link index resolves bare names and
+// titles against the vault and sets Broken if no such note exists.
+type Link struct {
+	Target string `json:"target"` // as written
+	ID     string `json:"id"`
+	Kind   string `json:"kind"` // "wiki" or "markdown"
+	Anchor string `json:"anchor,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Line   int    `json:"line"`
+	// Context is the line the link is on, trimmed
+	Context string `json:"context,omitempty"`
+	Broken  bool   `json:"broken,omitempty"`
+}
+
+// Backlink is a link to a note, seen from the note containing it.
+type Backlink struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Line    int    `json:"line"`
+	Context string `json:"context"` // the line the link is on
+}
+
+// Heading is an entry of a note's outline. Anchor is the heading's id in
+// the rendered HTML; Line is the line of the file it starts on.
+type Heading struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"`
+	Line   int    `json:"line"`
+}
+
+// Section is the part of a note under one heading, up to the next heading
+// of the same or a higher level.
+type Section struct {
+	Heading
+	Content string `json:"content"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// Task is a task list item ("- [ ] text") of a note. Due and Priority come
+// from "@2026-11-01" and "!high"/"!medium"/"!low" in the text, Tags from
+// its #hashtags. NoteID and NoteTitle are set when tasks of several notes
+// are listed together.
+type Task struct {
+	NoteID    string   `json:"noteId,omitempty"`
+	NoteTitle string   `json:"noteTitle,omitempty"`
+	Line      int      `json:"line"`
+	Text      string   `json:"text"`
+	Done      bool     `json:"done"`
+	Due       string   `json:"due,omitempty"`
+	Priority  string   `json:"priority,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// Snippet is a fenced code block of a note. Heading and Anchor name the
+// closest heading above it. NoteTitle is set when snippets of several
+// notes are listed together.
+type Snippet struct {
+	NoteID    string `json:"noteId"`
+	NoteTitle string `json:"noteTitle,omitempty"`
+	Line      int    `json:"line"`
+	Lang      string `json:"lang"`
+	Code      string `json:"code"`
+	Heading   string `json:"heading,omitempty"`
+	Anchor    string `json:"anchor,omitempty"`
+}
+
+// TrashItem is a deleted note waiting in the trash. ID identifies the
+// item for restore and purge; NoteID is where the note lived.
+type TrashItem struct {
+	ID        string    `json:"id"`
+	NoteID    string    `json:"noteId"`
+	Title     string    `json:"title"`
+	DeletedAt time.Time `json:"deletedAt"`
+	Size      int64     `json:"size"`
+}
+
+// Revision is a stored snapshot of a note's raw file content
+type Revision struct {
+	ID        string    `json:"id"`
+	NoteID    string    `json:"noteId"`
+	CreatedAt time.Time `json:"createdAt"`
+	Size      int64     `json:"size"`
+}
+
+// Folder is a directory in the vault, with the notes and folders it contains
+type Folder struct {
+	Path     string   `json:"path"` // Slash-separated, "" for the vault root
+	Name     string   `json:"name"`
+	Notes    []string `json:"notes"`
+	Children []Folder `json:"children"`
+}
+
+// TagCount is a tag and the number of notes carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
 }
 
 // NoteMetadata is the frontmatter/metadata of a note
 type NoteMetadata struct {
-	Title     string    `yaml:"title"`
-	Tags      []string  `yaml:"tags,omitempty"`
-	Created   string    `yaml:"created,omitempty"`
-	Updated   string    `yaml:"updated,omitempty"`
+	Title   string   `yaml:"title"`
+	Tags    []string `yaml:"tags,omitempty"`
+	Author  string   `yaml:"author,omitempty"`
+	Created string   `yaml:"created,omitempty"`
+	Updated string   `yaml:"updated,omitempty"`
+
+	// Extra holds all keys not listed above
+	Extra map[string]any `yaml:",inline"`
 }
@@ -0,0 +1,237 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCorrections caps the spellings a misspelled term is expanded to.
+const maxCorrections = 3
+
+// vocabulary is the set of terms in the index, read from notes_vocab, with
+// a trigram index for finding close spellings of a term.
+type vocabulary struct {
+	docs  map[string]int      // term -> number of notes containing it
+	terms []string            // sorted, for prefix lookups
+	grams map[string][]string // trigram -> terms containing it
+}
+
+// vocabulary returns the cached vocabulary, reading it again after the
+// index has changed.
+func (s *Service) vocabulary() (*vocabulary, error) {
+	s.vocabMu.Lock()
+	defer s.vocabMu.Unlock()
+	if s.vocab != nil {
+		return s.vocab, nil
+	}
+
+	// Terms found only in tag_terms are encoded tags, not words to suggest
+	rows, err := s.db.Query(`SELECT term, doc FROM notes_vocab
+		WHERE term IN (SELECT term FROM notes_vocab_col WHERE col != 'tag_terms')`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	v := &vocabulary{docs: make(map[string]int), grams: make(map[string][]string)}
+	for rows.Next() {
+		var term string
+		var docs int
+		if err := rows.Scan(&term, &docs); err != nil {
+			return nil, err
+		}
+		v.docs[term] = docs
+		v.terms = append(v.terms, term)
+		for _, g := range trigrams(term) {
+			v.grams[g] = append(v.grams[g], term)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(v.terms)
+	s.vocab = v
+	return v, nil
+}
+
+// invalidateVocab drops the cached vocabulary. Call it after committing a
+// change to notes_fts.
+func (s *Service) invalidateVocab() {
+	s.vocabMu.Lock()
+	s.vocab = nil
+	s.vocabMu.Unlock()
+}
+
+// hasPrefix reports whether some term starts with p.
+func (v *vocabulary) hasPrefix(p string) bool {
+	i := sort.SearchStrings(v.terms, p)
+	return i < len(v.terms) && strings.HasPrefix(v.terms[i], p)
+}
+
+// corrections returns up to n terms within a small edit distance of term
+// (one edit up to four characters, two above), closest and most common
+// first.
+func (v *vocabulary) corrections(term string, n int) []string {
+	runes := []rune(term)
+	maxDist := 1
+	if len(runes) > 4 {
+		maxDist = 2
+	}
+
+	seen := make(map[string]bool)
+	type candidate struct {
+		term string
+		dist int
+	}
+	var found []candidate
+	for _, g := range trigrams(term) {
+		for _, t := range v.grams[g] {
+			if seen[t] {
+				continue
+			}
+			seen[t] = true
+			other := []rune(t)
+			if abs(len(other)-len(runes)) > maxDist {
+				continue
+			}
+			if d := editDistance(runes, other); d <= maxDist {
+				found = append(found, candidate{t, d})
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.dist != b.dist {
+			return a.dist < b.dist
+		}
+		if v.docs[a.term] != v.docs[b.term] {
+			return v.docs[a.term] > v.docs[b.term]
+		}
+		return a.term < b.term
+	})
+
+	var out []string
+	for _, c := range found {
+		if len(out) == n {
+			break
+		}
+		out = append(out, c.term)
+	}
+	return out
+}
+
+// misspelled maps the offset of each misspelled word of items to its
+// likely spellings. Words shorter than three characters, known words and,
+// for prefix words, the start of a known word are left alone.
+func (v *vocabulary) misspelled(items []item) map[int][]string {
+	fixes := make(map[int][]string)
+	for _, it := range items {
+		if it.kind != itemWord || utf8.RuneCountInString(it.text) < 3 || bareword(it.text) != it.text {
+			continue
+		}
+		term := strings.ToLower(it.text)
+		if v.docs[term] > 0 || it.prefix && v.hasPrefix(term) {
+			continue
+		}
+		if c := v.corrections(term, maxCorrections); len(c) > 0 {
+			fixes[it.pos] = c
+		}
+	}
+	return fixes
+}
+
+// fuzzyRender renders words with known misspellings as an OR of the word
+// and its corrections.
+func fuzzyRender(fixes map[int][]string) func(item) string {
+	return func(it item) string {
+		s := renderItem(it)
+		c, ok := fixes[it.pos]
+		if !ok || it.kind != itemWord {
+			return s
+		}
+		alts := []string{s}
+		for _, t := range c {
+			alts = append(alts, bareword(t))
+		}
+		return "(" + strings.Join(alts, " OR ") + ")"
+	}
+}
+
+// suggest returns q with each misspelled word replaced by its best
+// correction, or "" if there is none.
+func suggest(q string, items []item, fixes map[int][]string) string {
+	if len(fixes) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	last := 0
+	for _, it := range items {
+		c, ok := fixes[it.pos]
+		if !ok || it.kind != itemWord {
+			continue
+		}
+		sb.WriteString(q[last:it.pos])
+		sb.WriteString(c[0])
+		last = it.pos + len(it.text)
+	}
+	sb.WriteString(q[last:])
+	return sb.String()
+}
+
+// markPrefix turns the last word of q, or the last word of a phrase
+// missing its closing quote, into a prefix match when q ends in it, i.e.
+// while the word is still being typed.
+func markPrefix(q string, items []item) {
+	n := len(items)
+	if n == 0 || items[n-1].end != len(q) {
+		return
+	}
+	if last := &items[n-1]; last.kind == itemWord || last.kind == itemPhrase && last.open {
+		last.prefix = true
+	}
+}
+
+// trigrams returns the three-character substrings of term padded with
+// "^" and "$", so short terms and word boundaries get trigrams too.
+func trigrams(term string) []string {
+	runes := []rune("^" + term + "$")
+	out := make([]string, 0, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		out = append(out, string(runes[i:i+3]))
+	}
+	return out
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and swaps of adjacent characters.
+func editDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"testing"
+
+	"marko-backend/internal/models"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"concurency", "concurrency", 1},
+		{"teh", "the", 1},
+		{"kitten", "sitting", 3},
+		{"", "go", 2},
+		{"über", "uber", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSearch_FuzzyAndPrefix(t *testing.T) {
+	s := newTestService(t)
+
+	notes := []models.Note{
+		{ID: "go.md", Title: "Go", Content: "Concurrency with goroutines and channels"},
+		{ID: "k8s.md", Title: "Clusters", Content: "Deploying to Kubernetes"},
+	}
+	for _, n := range notes {
+		if err := s.Index(n); err != nil {
+			t.Fatalf("Index: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query      string
+		opts       SearchOptions
+		want       int
+		suggestion string
+	}{
+		{"concurency", SearchOptions{}, 0, "concurrency"},
+		{"concurency", SearchOptions{Fuzzy: true}, 1, "concurrency"},
+		{"Gorutines AND channels", SearchOptions{Fuzzy: true}, 1, "goroutines AND channels"},
+		{"kube", SearchOptions{}, 0, ""},
+		{"kube", SearchOptions{Prefix: true}, 1, ""},
+		{"kube ", SearchOptions{Prefix: true}, 0, ""},
+		{"kubernets", SearchOptions{Fuzzy: true, Prefix: true}, 1, "kubernetes"},
+		{"go concurency", SearchOptions{Fuzzy: true, Prefix: true}, 1, "go concurrency"},
+		{"deploying kubernets chan", SearchOptions{Fuzzy: true, Prefix: true}, 0, "deploying kubernetes chan"},
+		{"kubernets + ", SearchOptions{Fuzzy: true, Prefix: true}, 1, "kubernetes + "},
+		{`"unbalanced`, SearchOptions{Prefix: true}, 0, ""},
+		{"AND (", SearchOptions{Prefix: true}, 0, ""},
+	}
+	for _, tt := range tests {
+		page, err := s.SearchPage(tt.query, tt.opts)
+		if err != nil {
+			t.Errorf("SearchPage(%q, %+v): %v", tt.query, tt.opts, err)
+			continue
+		}
+		if page.Total != tt.want || page.Suggestion != tt.suggestion {
+			t.Errorf("SearchPage(%q, %+v) = %d results, suggestion %q; want %d, %q",
+				tt.query, tt.opts, page.Total, page.Suggestion, tt.want, tt.suggestion)
+		}
+	}
+
+	// The vocabulary follows the index
+	if err := s.Index(models.Note{ID: "mutex.md", Title: "Mutexes", Content: "Locking"}); err != nil {
+		t.Fatalf("Index: %v", err)
+	}
+	if page, _ := s.SearchPage("mutexs", SearchOptions{Fuzzy: true}); page.Total != 1 {
+		t.Errorf("fuzzy search after reindex found %d notes, want 1", page.Total)
+	}
+}
@@ -0,0 +1,160 @@
+package search
+
+import (
+	"sort"
+	"strings"
+
+	"marko-backend/internal/models"
+)
+
+// GraphOptions selects the part of the graph to return.
+type GraphOptions struct {
+	// Center limits the graph to notes within Depth links of this note
+	// (in either direction). Empty means the whole vault.
+	Center string
+	Depth  int
+	// Tags adds tag nodes and tag membership edges.
+	Tags bool
+	// Orphans returns only the notes without any links.
+	Orphans bool
+}
+
+// Graph builds the note graph from the link index. Broken links are left
+// out; see BrokenLinks.
+func (s *Service) Graph(opts GraphOptions) (models.Graph, error) {
+	r, titles, err := s.resolver()
+	if err != nil {
+		return models.Graph{}, err
+	}
+
+	// Link edges, merged per (source, target) pair
+	type pair struct{ from, to string }
+	weights := make(map[pair]int)
+	rows, err := s.queryLinks("1")
+	if err != nil {
+		return models.Graph{}, err
+	}
+	for _, row := range rows {
+		l := resolve(r, row.source, row.link)
+		if l.Broken || l.ID == row.source {
+			continue
+		}
+		weights[pair{row.source, l.ID}]++
+	}
+
+	nodes := make(map[string]*models.GraphNode, len(titles))
+	for id, title := range titles {
+		nodes[id] = &models.GraphNode{ID: id, Label: title, Type: "note"}
+	}
+
+	var edges []models.GraphEdge
+	neighbours := make(map[string][]string)
+	for p, w := range weights {
+		edges = append(edges, models.GraphEdge{Source: p.from, Target: p.to, Type: "link", Weight: w})
+		nodes[p.from].Degree++
+		nodes[p.to].Degree++
+		neighbours[p.from] = append(neighbours[p.from], p.to)
+		neighbours[p.to] = append(neighbours[p.to], p.from)
+	}
+	for id, n := range nodes {
+		n.Orphan = len(neighbours[id]) == 0
+	}
+
+	if opts.Tags {
+		tagEdges, err := s.tagEdges(nodes)
+		if err != nil {
+			return models.Graph{}, err
+		}
+		edges = append(edges, tagEdges...)
+	}
+
+	keep := func(id string) bool { return true }
+	switch {
+	case opts.Orphans:
+		keep = func(id string) bool { return nodes[id].Orphan }
+	case opts.Center != "":
+		within := neighbourhood(opts.Center, opts.Depth, neighbours)
+		// Tags stay if one of the kept notes carries them
+		for _, e := range edges {
+			if e.Type == "tag" && within[e.Source] {
+				within[e.Target] = true
+			}
+		}
+		keep = func(id string) bool { return within[id] }
+	}
+
+	g := models.Graph{Nodes: []models.GraphNode{}, Edges: []models.GraphEdge{}}
+	for id, n := range nodes {
+		if keep(id) {
+			g.Nodes = append(g.Nodes, *n)
+		}
+	}
+	for _, e := range edges {
+		if keep(e.Source) && keep(e.Target) {
+			g.Edges = append(g.Edges, e)
+		}
+	}
+
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].Source != g.Edges[j].Source {
+			return g.Edges[i].Source < g.Edges[j].Source
+		}
+		return g.Edges[i].Target < g.Edges[j].Target
+	})
+	return g, nil
+}
+
+// tagEdges adds a node per tag to nodes and returns the note -> tag edges.
+// Tags are compared case-insensitively and labelled with their first
+// spelling.
+func (s *Service) tagEdges(nodes map[string]*models.GraphNode) ([]models.GraphEdge, error) {
+	rows, err := s.db.Query("SELECT id, tags FROM notes_fts WHERE tags != '' ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edges []models.GraphEdge
+	for rows.Next() {
+		var id, tags string
+		if err := rows.Scan(&id, &tags); err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, tag := range strings.Split(tags, ", ") {
+			tagID := "tag:" + strings.ToLower(tag)
+			if tag == "" || seen[tagID] {
+				continue
+			}
+			seen[tagID] = true
+
+			if nodes[tagID] == nil {
+				nodes[tagID] = &models.GraphNode{ID: tagID, Label: "#" + tag, Type: "tag"}
+			}
+			nodes[tagID].Degree++
+			nodes[id].Degree++
+			edges = append(edges, models.GraphEdge{Source: id, Target: tagID, Type: "tag", Weight: 1})
+		}
+	}
+	return edges, rows.Err()
+}
+
+// neighbourhood returns the notes at most depth links away from center.
+func neighbourhood(center string, depth int, neighbours map[string][]string) map[string]bool {
+	within := map[string]bool{center: true}
+	frontier := []string{center}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []string
+		for _, id := range frontier {
+			for _, n := range neighbours[id] {
+				if !within[n] {
+					within[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+		frontier = next
+	}
+	return within
+}
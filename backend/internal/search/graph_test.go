@@ -0,0 +1,58 @@
+package search
+
+import (
+	"testing"
+
+	"marko-backend/internal/models"
+)
+
+func TestGraph(t *testing.T) {
+	s := newTestService(t)
+
+	// a -> b -> c, a -> b twice, d alone; a and d share a tag
+	files := map[string]string{
+		"a.md": "---\ntags: [go, Intro]\n---\n[[b]] and again [[b]]",
+		"b.md": "[c](c.md) and [[missing]]",
+		"c.md": "---\ntags: [rust]\n---\nEnd of the line",
+		"d.md": "---\ntags: [GO]\n---\nNobody links here",
+	}
+	indexFiles(t, s, files)
+
+	g, err := s.Graph(GraphOptions{Tags: true})
+	if err != nil {
+		t.Fatalf("Graph: %v", err)
+	}
+	nodes := make(map[string]models.GraphNode)
+	for _, n := range g.Nodes {
+		nodes[n.ID] = n
+	}
+	if len(nodes) != 7 { // 4 notes, 3 tags
+		t.Errorf("expected 7 nodes, got %+v", g.Nodes)
+	}
+	if n := nodes["tag:go"]; n.Label != "#go" || n.Degree != 2 || n.Type != "tag" {
+		t.Errorf("unexpected tag node %+v", n)
+	}
+	if n := nodes["b.md"]; n.Degree != 2 || n.Orphan {
+		t.Errorf("unexpected node b %+v", n)
+	}
+	if n := nodes["d.md"]; n.Degree != 1 || !n.Orphan {
+		t.Errorf("d should be an orphan with one tag edge, got %+v", n)
+	}
+	if len(g.Edges) != 6 || g.Edges[0] != (models.GraphEdge{Source: "a.md", Target: "b.md", Type: "link", Weight: 2}) {
+		t.Errorf("unexpected edges %+v", g.Edges)
+	}
+
+	g, _ = s.Graph(GraphOptions{Center: "c.md", Depth: 1})
+	if len(g.Nodes) != 2 || g.Nodes[0].ID != "b.md" || g.Nodes[1].ID != "c.md" || len(g.Edges) != 1 {
+		t.Errorf("depth 1 around c = %+v", g)
+	}
+	g, _ = s.Graph(GraphOptions{Center: "c.md", Depth: 2, Tags: true})
+	if len(g.Nodes) != 6 { // a, b, c and the tags of a and c
+		t.Errorf("depth 2 around c = %+v", g.Nodes)
+	}
+
+	g, _ = s.Graph(GraphOptions{Orphans: true})
+	if len(g.Nodes) != 1 || g.Nodes[0].ID != "d.md" || len(g.Edges) != 0 {
+		t.Errorf("orphans = %+v", g)
+	}
+}
@@ -0,0 +1,180 @@
+package search
+
+import (
+	"database/sql"
+	"path"
+	"strings"
+
+	"marko-backend/internal/links"
+	"marko-backend/internal/models"
+)
+
+// writeLinks replaces the rows of note in the links table.
+func writeLinks(tx *sql.Tx, note models.Note) error {
+	if _, err := tx.Exec("DELETE FROM links WHERE id = ?", note.ID); err != nil {
+		return err
+	}
+	for _, l := range note.Links {
+		if _, err := tx.Exec(`INSERT INTO links (id, target, name, raw, kind, anchor, text, line, context)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			note.ID, l.ID, bareName(l), l.Target, l.Kind, l.Anchor, l.Text, l.Line, l.Context); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bareName is the lower-case name a folderless wikilink looks notes up
+// by, or "" for links that name a path.
+func bareName(l models.Link) string {
+	if l.Kind != links.Wiki.String() || strings.Contains(strings.Trim(l.Target, "/"), "/") {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(l.Target))
+}
+
+// resolver returns a links.Resolver over the indexed notes and their titles.
+func (s *Service) resolver() (*links.Resolver, map[string]string, error) {
+	rows, err := s.db.Query("SELECT id, title FROM notes_fts")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	titles := make(map[string]string)
+	var ids []string
+	for rows.Next() {
+		var id, title string
+		if err := rows.Scan(&id, &title); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+		titles[id] = title
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	r := links.NewResolver(ids)
+	for _, id := range ids {
+		r.AddTitle(id, titles[id])
+	}
+	return r, titles, nil
+}
+
+type linkRow struct {
+	source string
+	link   models.Link
+}
+
+func (s *Service) queryLinks(where string, args ...any) ([]linkRow, error) {
+	rows, err := s.db.Query(`SELECT id, target, raw, kind, anchor, text, line, context
+		FROM links WHERE `+where+` ORDER BY id, line`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []linkRow
+	for rows.Next() {
+		var r linkRow
+		if err := rows.Scan(&r.source, &r.link.ID, &r.link.Target, &r.link.Kind, &r.link.Anchor,
+			&r.link.Text, &r.link.Line, &r.link.Context); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// resolve sets the resolved ID and Broken flag of an indexed link.
+func resolve(r *links.Resolver, source string, l models.Link) models.Link {
+	kind := links.Markdown
+	if l.Kind == links.Wiki.String() {
+		kind = links.Wiki
+	}
+	id, exists, ok := r.Resolve(source, links.Link{Kind: kind, Target: l.Target})
+	if ok {
+		l.ID = id
+	}
+	l.Broken = !exists
+	return l
+}
+
+// Links returns the outgoing links of note id, resolved against the
+// indexed notes. Links to notes that do not exist are marked Broken.
+func (s *Service) Links(id string) ([]models.Link, error) {
+	r, _, err := s.resolver()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queryLinks("id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []models.Link{}
+	for _, row := range rows {
+		out = append(out, resolve(r, row.source, row.link))
+	}
+	return out, nil
+}
+
+// Backlinks returns the links from other notes that resolve to note id,
+// ordered by linking note and line.
+func (s *Service) Backlinks(id string) ([]models.Backlink, error) {
+	r, titles, err := s.resolver()
+	if err != nil {
+		return nil, err
+	}
+
+	// Candidates: links naming the path, or a bare name that could be
+	// the file name or title; the resolver decides which really match
+	name := strings.ToLower(strings.TrimSuffix(path.Base(id), ".md"))
+	title := strings.ToLower(titles[id])
+	rows, err := s.queryLinks("target = ? OR name IN (?, ?, ?)", id, name, name+".md", title)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []models.Backlink{}
+	for _, row := range rows {
+		l := resolve(r, row.source, row.link)
+		if l.Broken || !strings.EqualFold(l.ID, id) || row.source == id {
+			continue
+		}
+		out = append(out, models.Backlink{
+			ID:      row.source,
+			Title:   titles[row.source],
+			Line:    row.link.Line,
+			Context: row.link.Context,
+		})
+	}
+	return out, nil
+}
+
+// BrokenLink is a link whose target note does not exist.
+type BrokenLink struct {
+	Source string      `json:"source"`
+	Link   models.Link `json:"link"`
+}
+
+// BrokenLinks returns every link in the vault that resolves to no note.
+func (s *Service) BrokenLinks() ([]BrokenLink, error) {
+	r, _, err := s.resolver()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queryLinks("1")
+	if err != nil {
+		return nil, err
+	}
+
+	out := []BrokenLink{}
+	for _, row := range rows {
+		if l := resolve(r, row.source, row.link); l.Broken {
+			out = append(out, BrokenLink{Source: row.source, Link: l})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,66 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLinkIndex(t *testing.T) {
+	s := newTestService(t)
+
+	files := map[string]string{
+		"go/basics.md":   "---\ntitle: Go Basics\n---\nStart with [[channels]] and [the spec](../ref/spec.md).",
+		"go/channels.md": "---\ntitle: Channels\n---\nSee [[Go Basics|the basics]].\n\nAlso [[missing note]].",
+		"ref/spec.md":    "# Spec\n\nBack to [[go/basics]] and `[[not a link]]`.",
+		"rust/todo.md":   "Compare with [[channels]] here, [[basics]] and [[../escape]].",
+	}
+	indexFiles(t, s, files)
+
+	back, err := s.Backlinks("go/basics.md")
+	if err != nil {
+		t.Fatalf("Backlinks: %v", err)
+	}
+	var got []string
+	for _, b := range back {
+		got = append(got, b.ID)
+	}
+	// By title, by path and by file name from another folder
+	if want := "go/channels.md,ref/spec.md,rust/todo.md"; strings.Join(got, ",") != want {
+		t.Errorf("Backlinks = %s, want %s", strings.Join(got, ","), want)
+	}
+	if len(back) > 0 && (back[0].Title != "Channels" || back[0].Line != 4 || back[0].Context != "See [[Go Basics|the basics]].") {
+		t.Errorf("unexpected backlink %+v", back[0])
+	}
+
+	out, err := s.Links("go/channels.md")
+	if err != nil {
+		t.Fatalf("Links: %v", err)
+	}
+	if len(out) != 2 || out[0].ID != "go/basics.md" || out[0].Broken || out[0].Text != "the basics" ||
+		out[1].ID != "missing note.md" || !out[1].Broken {
+		t.Errorf("unexpected links %+v", out)
+	}
+
+	broken, err := s.BrokenLinks()
+	if err != nil {
+		t.Fatalf("BrokenLinks: %v", err)
+	}
+	var brokenIDs []string
+	for _, b := range broken {
+		brokenIDs = append(brokenIDs, b.Source+"->"+b.Link.Target)
+	}
+	if want := "go/channels.md->missing note,rust/todo.md->../escape"; strings.Join(brokenIDs, ",") != want {
+		t.Errorf("BrokenLinks = %s, want %s", strings.Join(brokenIDs, ","), want)
+	}
+
+	// Deleting the target breaks the links to it
+	if err := s.Delete("go/basics.md"); err != nil {
+		t.Fatal(err)
+	}
+	if back, _ := s.Backlinks("go/basics.md"); len(back) != 0 {
+		t.Errorf("backlinks of deleted note: %+v", back)
+	}
+	if out, _ := s.Links("go/basics.md"); len(out) != 0 {
+		t.Errorf("links of deleted note still indexed: %+v", out)
+	}
+}
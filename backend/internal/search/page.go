@@ -0,0 +1,88 @@
+package search
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"marko-backend/internal/models"
+)
+
+const (
+	// DefaultLimit is the page size used when SearchOptions.Limit is unset.
+	DefaultLimit = 20
+	// MaxLimit caps the page size a caller may request.
+	MaxLimit = 100
+)
+
+// Sort orders accepted by SearchOptions.Sort.
+const (
+	SortRelevance = "relevance"
+	SortUpdatedAt = "updatedAt"
+	SortTitle     = "title"
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort order")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// sortOrders maps SearchOptions.Sort to an ORDER BY clause. Every order
+// ends in id so pages are stable between requests.
+var sortOrders = map[string]string{
+	"":            "score, id",
+	SortRelevance: "score, id",
+	SortUpdatedAt: "updated_at DESC, id",
+	SortTitle:     "title COLLATE NOCASE, id",
+}
+
+// SearchOptions controls paging and ordering of search results. Cursor,
+// when set, takes precedence over Offset. CodeOnly restricts the query to
+// fenced code blocks. Fuzzy also matches close spellings of words that
+// are not in the index, and Prefix matches the word the query ends in as
+// a prefix, for search-as-you-type. Ranking, when set, replaces the
+// service's ranking for this search.
+type SearchOptions struct {
+	Limit    int
+	Offset   int
+	Cursor   string
+	Sort     string
+	CodeOnly bool
+	Fuzzy    bool
+	Prefix   bool
+	Ranking  *Ranking
+}
+
+// SearchResults is one page of search hits. NextCursor is empty on the
+// last page. Suggestion is the query with misspelled words corrected, set
+// when a fuzzy search corrected them or a search found nothing.
+type SearchResults struct {
+	Results    []models.Note `json:"results"`
+	Total      int           `json:"total"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+	Suggestion string        `json:"suggestion,omitempty"`
+}
+
+// Cursors are opaque to clients; today they carry the offset of the next
+// page. The note list pages with them too.
+const cursorPrefix = "o:"
+
+// EncodeCursor returns the cursor of the page starting at offset.
+func EncodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// DecodeCursor returns the offset a cursor from EncodeCursor carries, or
+// ErrInvalidCursor.
+func DecodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, ErrInvalidCursor
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return offset, nil
+}
@@ -0,0 +1,159 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryError is a syntax error in a search query. Pos is the byte offset
+// in the query where the problem was found.
+type QueryError struct {
+	Pos int    `json:"position"`
+	Msg string `json:"error"`
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// parseQuery compiles q like compileQuery, but rejects malformed input
+// with a *QueryError instead of repairing it. The grammar follows FTS5
+// precedence, NOT binding tighter than AND and AND tighter than OR:
+//
+//	or      = and { "OR" and }
+//	and     = not { ["AND"] not }
+//	not     = primary { "NOT" primary }
+//	primary = word | phrase | field:value | "(" or ")"
+//
+// Operators are always written out in the result, so groups and filters
+// may sit next to each other.
+func parseQuery(q string, items []item, render func(item) string) (string, error) {
+	p := &parser{q: q, items: items, render: render}
+	if len(items) == 0 {
+		return "", p.errorf(0, "empty query")
+	}
+	expr, err := p.or()
+	if err != nil {
+		return "", err
+	}
+	if it := p.peek(); it != nil {
+		// or() only stops early at a parenthesis it did not open
+		return "", p.errorf(it.pos, "unexpected )")
+	}
+	return expr, nil
+}
+
+type parser struct {
+	q      string
+	items  []item
+	i      int
+	render func(item) string
+}
+
+// peek returns the next item, or nil at the end of the query.
+func (p *parser) peek() *item {
+	if p.i < len(p.items) {
+		return &p.items[p.i]
+	}
+	return nil
+}
+
+func (p *parser) errorf(pos int, format string, args ...any) *QueryError {
+	return &QueryError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// isOperator reports whether the next item is the operator op.
+func (p *parser) isOperator(op string) bool {
+	it := p.peek()
+	return it != nil && it.kind == itemOperator && it.text == op
+}
+
+func (p *parser) or() (string, error) {
+	return p.binary("OR", p.and)
+}
+
+func (p *parser) not() (string, error) {
+	return p.binary("NOT", p.primary)
+}
+
+// binary parses operands of next joined by op.
+func (p *parser) binary(op string, next func() (string, error)) (string, error) {
+	left, err := next()
+	if err != nil {
+		return "", err
+	}
+	for p.isOperator(op) {
+		p.i++
+		right, err := next()
+		if err != nil {
+			return "", err
+		}
+		left += " " + op + " " + right
+	}
+	return left, nil
+}
+
+// and is like binary, but the operator may be left out between terms.
+func (p *parser) and() (string, error) {
+	parts := []string{}
+	for {
+		part, err := p.not()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+
+		if p.isOperator("AND") {
+			p.i++
+			continue
+		}
+		if it := p.peek(); it == nil || it.kind == itemOperator || it.kind == itemClose {
+			return strings.Join(parts, " AND "), nil
+		}
+	}
+}
+
+func (p *parser) primary() (string, error) {
+	it := p.peek()
+	if it == nil {
+		if p.i == 0 {
+			return "", p.errorf(len(p.q), "expected a term")
+		}
+		prev := p.items[p.i-1]
+		return "", p.errorf(len(p.q), "expected a term after %s", prev.text)
+	}
+	if it.open {
+		return "", p.errorf(it.pos+strings.LastIndexByte(p.q[it.pos:it.end], '"'), "unterminated quote")
+	}
+
+	switch it.kind {
+	case itemWord, itemPhrase:
+		p.i++
+		s := p.render(*it)
+		if s == "" {
+			return "", p.errorf(it.pos, "empty phrase")
+		}
+		return s, nil
+	case itemField:
+		p.i++
+		return it.text, nil
+	case itemOpen:
+		p.i++
+		if next := p.peek(); next != nil && next.kind == itemClose {
+			return "", p.errorf(it.pos, "empty parentheses")
+		}
+		expr, err := p.or()
+		if err != nil {
+			return "", err
+		}
+		if next := p.peek(); next == nil || next.kind != itemClose {
+			return "", p.errorf(it.pos, "unclosed (")
+		}
+		p.i++
+		return "(" + expr + ")", nil
+	case itemClose:
+		return "", p.errorf(it.pos, "unexpected )")
+	default:
+		return "", p.errorf(it.pos, "expected a term before %s", it.text)
+	}
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"errors"
+	"testing"
+
+	"marko-backend/internal/models"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"go channels", "go AND channels"},
+		{`"go channels" OR mutex`, `"go channels" OR mutex`},
+		{"a OR b c", "a OR b AND c"},
+		{"a NOT b OR c", "a NOT b OR c"},
+		{"(a OR b) c*", "(a OR b) AND c*"},
+		{"tag:go OR tag:rust", `tag_terms : "go" OR tag_terms : "rust"`},
+		{`title:"go channels" -x`, `title : "go channels" AND "-x"`},
+	}
+	for _, tt := range tests {
+		items, err := lex(tt.in)
+		if err != nil {
+			t.Fatalf("lex(%q): %v", tt.in, err)
+		}
+		if got, err := parseQuery(tt.in, items, renderItem); err != nil || got != tt.want {
+			t.Errorf("parseQuery(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	tests := []struct {
+		in  string
+		pos int
+		msg string
+	}{
+		{"  ", 0, "empty query"},
+		{"AND", 0, "expected a term before AND"},
+		{"go OR", 5, "expected a term after OR"},
+		{"go AND OR x", 7, "expected a term before OR"},
+		{`go "chan`, 3, "unterminated quote"},
+		{`title:"go`, 6, "unterminated quote"},
+		{`a ""`, 2, "empty phrase"},
+		{"a (b OR c", 2, "unclosed ("},
+		{"a ()", 2, "empty parentheses"},
+		{"a) b", 1, "unexpected )"},
+		{"go\x00x", 2, "control character"},
+		{`"a` + "\x1b" + `"`, 2, "control character"},
+	}
+	for _, tt := range tests {
+		items, err := lex(tt.in)
+		if err == nil {
+			_, err = parseQuery(tt.in, items, renderItem)
+		}
+		var qe *QueryError
+		if !errors.As(err, &qe) || qe.Pos != tt.pos || qe.Msg != tt.msg {
+			t.Errorf("parseQuery(%q) error = %v; want %q at %d", tt.in, err, tt.msg, tt.pos)
+		}
+	}
+}
+
+func TestSearch_InvalidQuery(t *testing.T) {
+	s := newTestService(t)
+	if err := s.Index(models.Note{ID: "a.md", Title: "A", Content: "go channels"}); err != nil {
+		t.Fatalf("Index: %v", err)
+	}
+
+	var qe *QueryError
+	if _, err := s.SearchPage(`"go`, SearchOptions{}); !errors.As(err, &qe) {
+		t.Errorf("expected a QueryError, got %v", err)
+	}
+	if _, err := s.SearchPage("go\x00", SearchOptions{}); !errors.As(err, &qe) {
+		t.Errorf("expected a QueryError for NUL, got %v", err)
+	}
+	// Search-as-you-type repairs the query instead
+	for _, q := range []string{`"go chan`, "go\x00chan"} {
+		if page, err := s.SearchPage(q, SearchOptions{Prefix: true}); err != nil || page.Total != 1 {
+			t.Errorf("prefix search %q = %d results, %v; want 1", q, page.Total, err)
+		}
+	}
+}
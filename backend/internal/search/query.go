@@ -0,0 +1,307 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// fieldColumns maps query field prefixes to notes_fts columns.
+var fieldColumns = map[string]string{
+	"title":    "title",
+	"tag":      "tag_terms",
+	"tags":     "tag_terms",
+	"content":  "content",
+	"body":     "content",
+	"heading":  "headings",
+	"headings": "headings",
+}
+
+// compileQuery translates the search box syntax into an FTS5 MATCH
+// expression. Terms of the form field:value become column filters:
+//
+//	title:foo    -> title : "foo"
+//	tag:go       -> tag_terms : "go"
+//	author:alice -> metadata : "author alice"
+//	status:draft -> metadata : "status draft"
+//
+// Values may be double-quoted to include spaces (title:"go channels").
+// Besides filters a query has words, phrases, AND/OR/NOT, parentheses and
+// trailing '*' prefixes; terms without an operator are combined with AND.
+//
+// compileQuery is lenient, for queries still being typed: any input gives
+// a valid expression. Words FTS5 would read as syntax are quoted,
+// unbalanced quotes and parentheses are closed or dropped, and operators
+// without operands are dropped. The result is empty when nothing
+// searchable is left. parseQuery is the strict counterpart.
+func compileQuery(q string) string {
+	items, _ := lex(blankControls(q))
+	return assemble(items, renderItem)
+}
+
+// assemble joins words, phrases, operators and parentheses into a valid
+// expression, dropping whatever cannot be placed.
+func assemble(items []item, render func(item) string) string {
+	type frame struct {
+		parts   []string
+		operand bool   // parts ends in an operand
+		group   bool   // that operand is a parenthesized group or a filter
+		op      string // operator waiting for its right operand
+	}
+	// add appends an operand, joining it with the pending operator. FTS5
+	// has no implicit AND next to a group, so it is spelled out there, and
+	// next to filters for readability.
+	add := func(f *frame, s string, group bool) {
+		if f.operand {
+			op := f.op
+			if op == "" && (group || f.group) {
+				op = "AND"
+			}
+			if op != "" {
+				f.parts = append(f.parts, op)
+			}
+		}
+		f.parts = append(f.parts, s)
+		f.operand, f.group, f.op = true, group, ""
+	}
+	stack := []*frame{{}}
+	closeGroup := func() {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if len(f.parts) > 0 {
+			add(stack[len(stack)-1], "("+strings.Join(f.parts, " ")+")", true)
+		}
+	}
+
+	for _, it := range items {
+		top := stack[len(stack)-1]
+		switch it.kind {
+		case itemWord, itemPhrase:
+			// render may expand a word into a group, e.g. fuzzyRender
+			if s := render(it); s != "" {
+				add(top, s, strings.HasPrefix(s, "("))
+			}
+		case itemField:
+			add(top, it.text, true)
+		case itemOperator:
+			if top.operand {
+				top.op = it.text
+			}
+		case itemOpen:
+			stack = append(stack, &frame{})
+		case itemClose:
+			if len(stack) > 1 {
+				closeGroup()
+			}
+		}
+	}
+	for len(stack) > 1 {
+		closeGroup()
+	}
+	return strings.Join(stack[0].parts, " ")
+}
+
+// renderItem renders a word or phrase as an FTS5 string.
+func renderItem(it item) string {
+	var s string
+	switch {
+	case it.kind == itemWord:
+		s = bareword(it.text)
+	case strings.TrimSpace(it.text) != "":
+		s = phrase(it.text)
+	default:
+		return ""
+	}
+	if it.prefix {
+		s += "*"
+	}
+	return s
+}
+
+// bareword returns w unquoted if FTS5 reads it as a plain word, and as a
+// phrase otherwise.
+func bareword(w string) string {
+	if w == "NEAR" {
+		return phrase(w)
+	}
+	for _, r := range w {
+		if r < utf8.RuneSelf && r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return phrase(w)
+		}
+	}
+	return w
+}
+
+// compileField turns a field:value token into a column filter.
+func compileField(tok string) (string, bool) {
+	i := strings.IndexByte(tok, ':')
+	if i <= 0 || i == len(tok)-1 {
+		return "", false
+	}
+	field, value := strings.ToLower(tok[:i]), tok[i+1:]
+	for _, r := range field {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '.' {
+			return "", false
+		}
+	}
+	if strings.HasPrefix(value, "/") { // URLs like http://...
+		return "", false
+	}
+	value = strings.Trim(value, `"`)
+	if value == "" {
+		return "", false
+	}
+
+	if col, ok := fieldColumns[field]; ok {
+		if col == "tag_terms" {
+			value = tagTerm(strings.TrimPrefix(value, "#"))
+		}
+		return col + " : " + phrase(value), true
+	}
+	return "metadata : " + phrase(field+" "+value), true
+}
+
+// tagTerm encodes a tag as a single FTS5 term, so a tag filter matches
+// whole tags only: tag:go must not match go-lang, which the tokenizer
+// would split into "go" and "lang". Letters and digits are kept, lower
+// case; 'z' and every other character are escaped as 'z', the hex code
+// point and 'z' again ("go-lang" becomes "goz2dzlang").
+func tagTerm(tag string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(tag) {
+		if r != 'z' && (unicode.IsLetter(r) || unicode.IsNumber(r)) {
+			sb.WriteRune(r)
+		} else {
+			fmt.Fprintf(&sb, "z%xz", r)
+		}
+	}
+	return sb.String()
+}
+
+// phrase quotes s as an FTS5 string, doubling embedded quotes.
+func phrase(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// itemKind classifies the items of a search query.
+type itemKind int
+
+const (
+	itemWord     itemKind = iota
+	itemPhrase            // a double-quoted string
+	itemField             // a field:value filter, already compiled
+	itemOperator          // AND, OR or NOT
+	itemOpen
+	itemClose
+)
+
+// item is a lexical item of a search query. pos and end are its byte
+// offsets in the query.
+type item struct {
+	kind   itemKind
+	text   string // word without '*', phrase without quotes, operator or filter
+	prefix bool   // followed by '*'
+	open   bool   // has a double quote that is never closed
+	pos    int
+	end    int
+}
+
+// lex splits q into items. Words run up to whitespace or a parenthesis
+// and may contain double-quoted runs (title:"go channels"); a phrase
+// missing its closing quote runs to the end of q. Control characters fail
+// with a *QueryError: they have no place in a query, and a NUL would cut
+// the expression short in SQLite.
+func lex(q string) ([]item, error) {
+	if i := strings.IndexFunc(q, isControl); i >= 0 {
+		return nil, &QueryError{Pos: i, Msg: "control character"}
+	}
+	var items []item
+	for i := 0; i < len(q); {
+		r, size := utf8.DecodeRuneInString(q[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			items = append(items, item{kind: itemOpen, text: "(", pos: i, end: i + 1})
+			i++
+		case r == ')':
+			items = append(items, item{kind: itemClose, text: ")", pos: i, end: i + 1})
+			i++
+		case r == '"':
+			it := item{kind: itemPhrase, pos: i, end: len(q), text: q[i+1:], open: true}
+			if j := strings.IndexByte(q[i+1:], '"'); j >= 0 {
+				it.text, it.end, it.open = q[i+1:i+1+j], i+2+j, false
+			}
+			if strings.HasPrefix(q[it.end:], "*") {
+				it.prefix = true
+				it.end++
+			}
+			items = append(items, it)
+			i = it.end
+		default:
+			j, open := i, false
+			for j < len(q) {
+				r, size := utf8.DecodeRuneInString(q[j:])
+				if unicode.IsSpace(r) || r == '(' || r == ')' {
+					break
+				}
+				if r == '"' {
+					k := strings.IndexByte(q[j+1:], '"')
+					if k < 0 {
+						j, open = len(q), true
+						break
+					}
+					j += k + 2
+					continue
+				}
+				j += size
+			}
+			if it, ok := lexWord(q[i:j], i, j); ok {
+				it.open = open
+				items = append(items, it)
+			}
+			i = j
+		}
+	}
+	return items, nil
+}
+
+func isControl(r rune) bool {
+	return unicode.IsControl(r) && !unicode.IsSpace(r)
+}
+
+// blankControls replaces the control characters of q with spaces, byte
+// for byte, so offsets into q stay valid.
+func blankControls(q string) string {
+	if strings.IndexFunc(q, isControl) < 0 {
+		return q
+	}
+	var sb strings.Builder
+	for _, r := range q {
+		if isControl(r) {
+			sb.WriteString(strings.Repeat(" ", utf8.RuneLen(r)))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// lexWord classifies a run of non-space characters, dropping runs
+// without letters or digits.
+func lexWord(tok string, pos, end int) (item, bool) {
+	switch tok {
+	case "AND", "OR", "NOT":
+		return item{kind: itemOperator, text: tok, pos: pos, end: end}, true
+	}
+	if f, ok := compileField(tok); ok {
+		return item{kind: itemField, text: f, pos: pos, end: end}, true
+	}
+	// Punctuation alone, like "+" or "*", tokenizes to nothing
+	word := strings.TrimRight(tok, "*")
+	if strings.IndexFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+		return item{}, false
+	}
+	return item{kind: itemWord, text: word, prefix: word != tok, pos: pos, end: end}, true
+}
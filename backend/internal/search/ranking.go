@@ -0,0 +1,92 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var ErrInvalidRanking = errors.New("invalid ranking")
+
+// Ranking weighs the parts of a note when results are ordered by
+// relevance. The weights scale the bm25 score of a match in each part;
+// the note's path counts like Body.
+//
+// Recency boosts recently updated notes: a note updated just now scores
+// up to 1+Recency times as high as an old one, a note HalfLife old
+// 1+Recency/2 times. 0 turns the boost off.
+type Ranking struct {
+	Title    float64       `json:"title"`
+	Tags     float64       `json:"tags"`
+	Headings float64       `json:"headings"`
+	Body     float64       `json:"body"`
+	Metadata float64       `json:"metadata"` // author and custom frontmatter
+	Code     float64       `json:"code"`
+	Recency  float64       `json:"recency"`
+	HalfLife time.Duration `json:"halfLife"`
+}
+
+// DefaultRanking favours title hits over tags, tags over headings and
+// headings over the rest of the note, without a recency boost.
+var DefaultRanking = Ranking{
+	Title:    10,
+	Tags:     5,
+	Headings: 3,
+	Body:     1,
+	Metadata: 1,
+	Code:     1,
+	HalfLife: 30 * 24 * time.Hour,
+}
+
+// Weights maps the names accepted for the column weights of a Ranking to
+// its fields, for parsing them from configuration.
+func (r *Ranking) Weights() map[string]*float64 {
+	return map[string]*float64{
+		"title":    &r.Title,
+		"tags":     &r.Tags,
+		"headings": &r.Headings,
+		"body":     &r.Body,
+		"metadata": &r.Metadata,
+		"code":     &r.Code,
+	}
+}
+
+// Validate rejects weights and boosts that are negative or not finite,
+// and a recency boost without a half life.
+func (r Ranking) Validate() error {
+	for name, w := range r.Weights() {
+		if !finite(*w) {
+			return fmt.Errorf("%w: %s weight %v", ErrInvalidRanking, name, *w)
+		}
+	}
+	if !finite(r.Recency) {
+		return fmt.Errorf("%w: recency %v", ErrInvalidRanking, r.Recency)
+	}
+	if r.Recency > 0 && r.HalfLife <= 0 {
+		return fmt.Errorf("%w: recency needs a positive half life", ErrInvalidRanking)
+	}
+	return nil
+}
+
+// finite reports whether f is a usable weight: not negative, infinite or
+// NaN.
+func finite(f float64) bool {
+	return f >= 0 && !math.IsInf(f, 1)
+}
+
+// scoreSQL scores a match: bm25 with per-column weights (id, title,
+// content, tags, metadata, code, headings, tag_terms), scaled by the
+// recency boost.
+// Like rank, lower is better. Use it with scoreArgs.
+const scoreSQL = "bm25(notes_fts, ?, ?, ?, ?, ?, ?, ?, ?) * (1 + ? * ? / (? + max(? - updated_at, 0)))"
+
+// scoreArgs returns the parameters of scoreSQL for r at time now.
+func (r Ranking) scoreArgs(now time.Time) []any {
+	halfLife := float64(r.HalfLife)
+	if halfLife <= 0 {
+		halfLife = 1 // unused: Recency is 0
+	}
+	return []any{r.Body, r.Title, r.Body, r.Tags, r.Metadata, r.Code, r.Headings, r.Tags,
+		r.Recency, halfLife, halfLife, now.UnixNano()}
+}
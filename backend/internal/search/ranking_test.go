@@ -0,0 +1,151 @@
+package search
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"marko-backend/internal/filesystem"
+	"marko-backend/internal/models"
+)
+
+// seedNow is the reference time of the seed index: seed-note-N.md was
+// last updated N days earlier.
+var seedNow = time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+// newSeedService indexes the seed notes of data/notes.
+func newSeedService(t *testing.T) *Service {
+	t.Helper()
+	s := newTestService(t)
+
+	paths, err := filepath.Glob("../../../data/notes/seed-note-*.md")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no seed notes found: %v", err)
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := filepath.Base(path)
+		n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(id, "seed-note-"), ".md"))
+		note := filesystem.ParseNoteContent(id, content, seedNow.AddDate(0, 0, -n))
+		if err := s.Index(note); err != nil {
+			t.Fatalf("Index %s: %v", id, err)
+		}
+	}
+	return s
+}
+
+func seedIDs(results []models.Note) []string {
+	out := make([]string, len(results))
+	for i, n := range results {
+		out[i] = strings.TrimSuffix(strings.TrimPrefix(n.ID, "seed-note-"), ".md")
+	}
+	return out
+}
+
+// expectTiers checks that results come in tiers: the notes of each tier,
+// in any order, before those of the next.
+func expectTiers(t *testing.T, query string, got []string, tiers ...[]string) {
+	t.Helper()
+	var want int
+	for _, tier := range tiers {
+		want += len(tier)
+	}
+	if len(got) != want {
+		t.Errorf("%q: got %v, want tiers %v", query, got, tiers)
+		return
+	}
+	for _, tier := range tiers {
+		in := make(map[string]bool)
+		for _, id := range tier {
+			in[id] = true
+		}
+		for _, id := range got[:len(tier)] {
+			if !in[id] {
+				t.Errorf("%q: got %v, want tiers %v", query, got, tiers)
+				return
+			}
+		}
+		got = got[len(tier):]
+	}
+}
+
+func TestRelevance_Seed(t *testing.T) {
+	s := newSeedService(t)
+	recent := DefaultRanking
+	recent.Recency = 5
+
+	tests := []struct {
+		query   string
+		ranking *Ranking
+		tiers   [][]string
+	}{
+		// Notes about a topic before notes that mention it in passing
+		{"router", nil, [][]string{{"11", "27"}, {"1", "6", "13"}}},
+		{"generics", nil, [][]string{{"8", "15", "21", "45"}, {"5", "9", "14"}}},
+		// Title before tags
+		{"tutorial", nil, [][]string{{"19", "23", "40", "42", "45", "46", "49"}, {"4", "5", "7", "9"}}},
+		{"rust draft", nil, [][]string{{"37", "39"}}},
+		// Recency orders notes of the same standing, newest first...
+		{"draft", &recent, [][]string{{"16"}, {"17"}, {"20"}, {"26"}, {"36"}, {"37"}, {"39"}, {"43"}}},
+		// ...but does not lift a passing mention above the topic's notes
+		{"generics", &recent, [][]string{{"8"}, {"15"}, {"21"}, {"45"}, {"5", "9", "14"}}},
+	}
+	for _, tt := range tests {
+		page, err := s.SearchPage(tt.query, SearchOptions{Limit: MaxLimit, Ranking: tt.ranking})
+		if err != nil {
+			t.Fatalf("SearchPage(%q): %v", tt.query, err)
+		}
+		expectTiers(t, tt.query, seedIDs(page.Results), tt.tiers...)
+	}
+}
+
+func TestRelevance_Weights(t *testing.T) {
+	s := newTestService(t)
+
+	// "zebra" once in a different part of each note
+	notes := []models.Note{
+		{ID: "body.md", Title: "One", Content: "a zebra grazes"},
+		{ID: "heading.md", Title: "Two", Content: "# Zebra\ngrazes", Outline: []models.Heading{{Level: 1, Text: "Zebra"}}},
+		{ID: "tags.md", Title: "Three", Content: "it grazes", Tags: []string{"zebra"}},
+		{ID: "title.md", Title: "Zebra", Content: "it grazes"},
+	}
+	for _, n := range notes {
+		if err := s.Index(n); err != nil {
+			t.Fatalf("Index: %v", err)
+		}
+	}
+
+	bodyFirst := DefaultRanking
+	bodyFirst.Body = 50
+	tests := []struct {
+		ranking *Ranking
+		want    string
+	}{
+		{nil, "title.md tags.md heading.md body.md"},
+		{&bodyFirst, "heading.md body.md title.md tags.md"},
+	}
+	for _, tt := range tests {
+		page, err := s.SearchPage("zebra", SearchOptions{Ranking: tt.ranking})
+		if err != nil {
+			t.Fatalf("SearchPage: %v", err)
+		}
+		var got []string
+		for _, n := range page.Results {
+			got = append(got, n.ID)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("ranking %+v: got %v, want %s", tt.ranking, got, tt.want)
+		}
+	}
+
+	if _, err := s.SearchPage("zebra", SearchOptions{Ranking: &Ranking{Title: -1}}); !errors.Is(err, ErrInvalidRanking) {
+		t.Errorf("negative weight: got %v, want ErrInvalidRanking", err)
+	}
+}
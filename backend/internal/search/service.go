@@ -2,20 +2,49 @@ package search
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"marko-backend/internal/models"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// Options configures a Service. The zero value uses DefaultRanking.
+type Options struct {
+	// Ranking orders results by relevance unless a search overrides it.
+	Ranking Ranking
+}
+
 type Service struct {
-	db *sql.DB
+	db      *sql.DB
+	ranking Ranking
+
+	// mu serializes index writes so a background Sync cannot overwrite a
+	// newer version indexed by a request handler with stale content.
+	mu sync.Mutex
+
+	// vocab caches the index vocabulary for fuzzy search; it is dropped
+	// whenever notes_fts changes.
+	vocabMu sync.Mutex
+	vocab   *vocabulary
 }
 
-func NewService(dataDir string) (*Service, error) {
+func NewService(dataDir string, opts Options) (*Service, error) {
+	ranking := opts.Ranking
+	if ranking == (Ranking{}) {
+		ranking = DefaultRanking
+	}
+	if err := ranking.Validate(); err != nil {
+		return nil, err
+	}
+
 	dbPath := filepath.Join(dataDir, "index.db")
 
 	// Ensure directory exists
@@ -28,7 +57,7 @@ func NewService(dataDir string) (*Service, error) {
 		return nil, err
 	}
 
-	s := &Service{db: db}
+	s := &Service{db: db, ranking: ranking}
 	if err := s.initSchema(); err != nil {
 		db.Close()
 		return nil, err
@@ -37,18 +66,129 @@ func NewService(dataDir string) (*Service, error) {
 	return s, nil
 }
 
+// schemaVersion is stored in PRAGMA user_version. Bump it whenever the
+// index layout changes: the index only holds derived data, so older
+// layouts are dropped and rebuilt by the next Sync.
+const schemaVersion = 7
+
+// noteTables hold rows derived from a note, keyed by the note's ID in
+// their id column. Deleting a note clears it from all of them.
+var noteTables = []string{"notes_fts", "notes_state", "links", "tasks", "snippets"}
+
 func (s *Service) initSchema() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version < schemaVersion {
+		if version > 0 || s.hasTable("notes_fts") {
+			log.Printf("Search index schema v%d is outdated, rebuilding as v%d", version, schemaVersion)
+		}
+		for _, table := range noteTables {
+			if _, err := s.db.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Create FTS5 virtual table
 	// We use contentless table if we didn't want to store data,
 	// but we might want snippets, so standard FTS is fine.
+	// tags holds the note's tags separated by ", ", metadata holds the
+	// remaining frontmatter flattened to "key value" lines (author included),
+	// code the note's fenced code blocks (also part of content, but kept
+	// apart so a search can be restricted to code), headings the text of the
+	// note's headings (weighted apart when ranking), tag_terms each tag
+	// as a single term (see tagTerm) so tag filters match whole tags, and
+	// updated_at the note's modification time in nanoseconds, used for
+	// sorting and ranking only.
+	// notes_state records what was indexed for each note (file mtime in
+	// nanoseconds and content hash) so startup can skip unchanged notes.
+	// An mtime of 0 means "unknown": the hash is compared instead.
+	// links holds the outgoing links of each note (id) as written; target
+	// is the path they name and name the lower-case bare name of a
+	// [[wikilink]] without folder, which may match a file name or title
+	// anywhere. Links are resolved against the vault when queried.
+	// tasks holds the task list items of each note with the note's title;
+	// tags are the task's own hashtags and match_tags those plus the
+	// note's tags, lower-case and wrapped in "|" for filtering. due is
+	// "YYYY-MM-DD" or empty.
+	// snippets holds the fenced code blocks of each note with the heading
+	// above them.
+	// notes_vocab and notes_vocab_col are read-only views of the terms in
+	// notes_fts, overall and per column, used to correct misspelled search
+	// terms.
 	query := `
-	CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(id, title, content);
+	CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(id, title, content, tags, metadata, code, headings, tag_terms, updated_at UNINDEXED);
+	CREATE VIRTUAL TABLE IF NOT EXISTS notes_vocab USING fts5vocab(notes_fts, 'row');
+	CREATE VIRTUAL TABLE IF NOT EXISTS notes_vocab_col USING fts5vocab(notes_fts, 'col');
+	CREATE TABLE IF NOT EXISTS notes_state (
+		id TEXT PRIMARY KEY,
+		mtime INTEGER NOT NULL,
+		hash TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS links (
+		id TEXT NOT NULL,
+		target TEXT NOT NULL COLLATE NOCASE,
+		name TEXT NOT NULL,
+		raw TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		anchor TEXT NOT NULL,
+		text TEXT NOT NULL,
+		line INTEGER NOT NULL,
+		context TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS links_id ON links(id);
+	CREATE INDEX IF NOT EXISTS links_target ON links(target);
+	CREATE INDEX IF NOT EXISTS links_name ON links(name);
+	CREATE TABLE IF NOT EXISTS tasks (
+		id TEXT NOT NULL,
+		title TEXT NOT NULL,
+		line INTEGER NOT NULL,
+		text TEXT NOT NULL,
+		done INTEGER NOT NULL,
+		due TEXT NOT NULL,
+		priority TEXT NOT NULL,
+		tags TEXT NOT NULL,
+		match_tags TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS tasks_id ON tasks(id);
+	CREATE INDEX IF NOT EXISTS tasks_due ON tasks(due);
+	CREATE TABLE IF NOT EXISTS snippets (
+		id TEXT NOT NULL,
+		title TEXT NOT NULL,
+		line INTEGER NOT NULL,
+		lang TEXT NOT NULL,
+		code TEXT NOT NULL,
+		heading TEXT NOT NULL,
+		anchor TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS snippets_id ON snippets(id);
+	CREATE INDEX IF NOT EXISTS snippets_lang ON snippets(lang);
 	`
-	_, err := s.db.Exec(query)
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+
+	_, err := s.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion))
 	return err
 }
 
+func (s *Service) hasTable(name string) bool {
+	var n int
+	s.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = ?", name).Scan(&n)
+	return n > 0
+}
+
 func (s *Service) Index(note models.Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.index(note, 0)
+}
+
+// index upserts a note and its state. Callers must hold s.mu.
+func (s *Service) index(note models.Note, mtime int64) error {
 	// Upsert: Delete then Insert (simplest for FTS)
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -63,46 +203,292 @@ func (s *Service) Index(note models.Note) error {
 	}
 
 	// Insert new
-	_, err = tx.Exec("INSERT INTO notes_fts (id, title, content) VALUES (?, ?, ?)", note.ID, note.Title, note.Content)
+	_, err = tx.Exec(insertNoteSQL, noteRow(note)...)
 	if err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	if err := upsertState(tx, note.ID, mtime, note.Version); err != nil {
+		return err
+	}
+	if err := writeLinks(tx, note); err != nil {
+		return err
+	}
+	if err := writeTasks(tx, note); err != nil {
+		return err
+	}
+	if err := writeSnippets(tx, note); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.invalidateVocab()
+	return nil
 }
 
-func (s *Service) Delete(id string) error {
-	_, err := s.db.Exec("DELETE FROM notes_fts WHERE id = ?", id)
+// Column indexes of notes_fts, for snippet().
+const (
+	contentColumn = 2
+	codeColumn    = 5
+)
+
+const insertNoteSQL = "INSERT INTO notes_fts (id, title, content, tags, metadata, code, headings, tag_terms, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+// noteRow returns the notes_fts column values for a note.
+func noteRow(note models.Note) []any {
+	var updated int64
+	if !note.UpdatedAt.IsZero() {
+		updated = note.UpdatedAt.UnixNano()
+	}
+	code := make([]string, len(note.Snippets))
+	for i, sn := range note.Snippets {
+		code[i] = sn.Code
+	}
+	headings := make([]string, len(note.Outline))
+	for i, h := range note.Outline {
+		headings[i] = h.Text
+	}
+	terms := make([]string, len(note.Tags))
+	for i, tag := range note.Tags {
+		terms[i] = tagTerm(tag)
+	}
+	return []any{note.ID, note.Title, note.Content, strings.Join(note.Tags, ", "), flattenMetadata(note),
+		strings.Join(code, "\n"), strings.Join(headings, "\n"), strings.Join(terms, " "), updated}
+}
+
+// flattenMetadata renders the author and custom frontmatter as "key value"
+// lines, so a field filter like author:alice can match the phrase
+// "author alice". Nested keys are joined with dots.
+func flattenMetadata(note models.Note) string {
+	var sb strings.Builder
+	if note.Author != "" {
+		sb.WriteString("author " + note.Author + "\n")
+	}
+
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		switch t := v.(type) {
+		case map[string]any:
+			keys := make([]string, 0, len(t))
+			for k := range t {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(prefix+"."+k, t[k])
+			}
+		case []any:
+			for _, item := range t {
+				walk(prefix, item)
+			}
+		case nil:
+		default:
+			fmt.Fprintf(&sb, "%s %v\n", strings.TrimPrefix(prefix, "."), t)
+		}
+	}
+	walk("", note.Metadata)
+	return sb.String()
+}
+
+func upsertState(tx *sql.Tx, id string, mtime int64, hash string) error {
+	_, err := tx.Exec(`
+		INSERT INTO notes_state (id, mtime, hash) VALUES (?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET mtime = excluded.mtime, hash = excluded.hash`,
+		id, mtime, hash)
 	return err
 }
 
+func (s *Service) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.delete(id)
+}
+
+// delete removes a note and its state. Callers must hold s.mu.
+func (s *Service) delete(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range noteTables {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE id = ?", id); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.invalidateVocab()
+	return nil
+}
+
+// DeletePrefix removes every note whose ID starts with prefix, e.g. all
+// notes of a folder that was deleted as a whole.
+func (s *Service) DeletePrefix(prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range noteTables {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE substr(id, 1, length(?)) = ?", prefix, prefix); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.invalidateVocab()
+	return nil
+}
+
+// Search returns the first page of results for query ordered by
+// relevance. Use SearchPage for pagination and other orderings.
 func (s *Service) Search(query string) ([]models.Note, error) {
-	// Simple prefix search possibility, but standard match is fine
-	// FTS5 syntax: MATCH 'query'
-	// We'll wrap in wildcards for partial match convenience if user wants
+	page, err := s.SearchPage(query, SearchOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return page.Results, nil
+}
+
+// SearchPage runs query and returns one page of results together with
+// the total number of hits. A malformed query fails with a *QueryError,
+// unless opts.Prefix is set.
+func (s *Service) SearchPage(query string, opts SearchOptions) (SearchResults, error) {
+	order, ok := sortOrders[opts.Sort]
+	if !ok {
+		return SearchResults{}, fmt.Errorf("%w: %q", ErrInvalidSort, opts.Sort)
+	}
+	if opts.Ranking != nil {
+		if err := opts.Ranking.Validate(); err != nil {
+			return SearchResults{}, err
+		}
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	offset := opts.Offset
+	if opts.Cursor != "" {
+		var err error
+		if offset, err = DecodeCursor(opts.Cursor); err != nil {
+			return SearchResults{}, err
+		}
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	if opts.Prefix {
+		query = blankControls(query)
+	}
+	items, err := lex(query)
+	if err != nil {
+		return SearchResults{}, err
+	}
+	if opts.Prefix {
+		markPrefix(query, items)
+	}
+	render := renderItem
+	var fixes map[int][]string
+	if opts.Fuzzy {
+		v, err := s.vocabulary()
+		if err != nil {
+			return SearchResults{}, err
+		}
+		fixes = v.misspelled(items)
+		render = fuzzyRender(fixes)
+	}
+
+	// Prefix queries are searched while they are typed, so incomplete
+	// syntax is repaired instead of rejected
+	var match string
+	if opts.Prefix {
+		match = assemble(items, render)
+	} else if match, err = parseQuery(query, items, render); err != nil {
+		return SearchResults{}, err
+	}
+	page := SearchResults{Results: []models.Note{}}
+	snippetColumn := contentColumn
+	if match == "" {
+		return page, nil
+	}
+	if opts.CodeOnly {
+		match, snippetColumn = "code : ("+match+")", codeColumn
+	}
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM notes_fts WHERE notes_fts MATCH ?", match).Scan(&page.Total); err != nil {
+		return SearchResults{}, err
+	}
+	if fixes == nil && page.Total == 0 {
+		// Nothing found: look for a misspelling to suggest instead
+		v, err := s.vocabulary()
+		if err != nil {
+			return SearchResults{}, err
+		}
+		fixes = v.misspelled(items)
+	}
+	page.Suggestion = suggest(query, items, fixes)
+
+	ranking := s.ranking
+	if opts.Ranking != nil {
+		ranking = *opts.Ranking
+	}
+	args := append([]any{snippetColumn}, ranking.scoreArgs(time.Now())...)
 	rows, err := s.db.Query(`
-		SELECT id, title, snippet(notes_fts, 2, '<b>', '</b>', '...', 64) 
+		SELECT id, title, snippet(notes_fts, ?, '<b>', '</b>', '...', 64), tags, updated_at, `+scoreSQL+` AS score
 		FROM notes_fts 
 		WHERE notes_fts MATCH ? 
-		ORDER BY rank 
-		LIMIT 20`, query)
+		ORDER BY `+order+`
+		LIMIT ? OFFSET ?`, append(args, match, limit, offset)...)
 	if err != nil {
-		return nil, err
+		return SearchResults{}, err
 	}
 	defer rows.Close()
 
-	results := []models.Note{}
 	for rows.Next() {
 		var n models.Note
-		var snippet string
-		if err := rows.Scan(&n.ID, &n.Title, &snippet); err != nil {
+		var snippet, tags string
+		var updated int64
+		var score float64
+		if err := rows.Scan(&n.ID, &n.Title, &snippet, &tags, &updated, &score); err != nil {
 			continue // Skip bad rows
 		}
 		// We smuggle the snippet into Content for display in search results
 		n.Content = snippet
-		results = append(results, n)
+		if tags != "" {
+			n.Tags = strings.Split(tags, ", ")
+		}
+		if updated != 0 {
+			n.UpdatedAt = time.Unix(0, updated)
+		}
+		page.Results = append(page.Results, n)
 	}
-	return results, nil
+	if err := rows.Err(); err != nil {
+		return SearchResults{}, err
+	}
+
+	if next := offset + limit; next < page.Total {
+		page.NextCursor = EncodeCursor(next)
+	}
+	return page, nil
+}
+
+// Ranking returns the ranking searches use unless they override it.
+func (s *Service) Ranking() Ranking {
+	return s.ranking
 }
 
 func (s *Service) Close() error {
@@ -110,6 +496,9 @@ func (s *Service) Close() error {
 }
 
 func (s *Service) ReindexAll(notes []models.Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
@@ -117,22 +506,44 @@ func (s *Service) ReindexAll(notes []models.Note) error {
 	defer tx.Rollback()
 
 	// Clear all
-	if _, err := tx.Exec("DELETE FROM notes_fts"); err != nil {
-		return err
+	for _, table := range noteTables {
+		if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+			return err
+		}
 	}
 
 	// Batch insert
-	stmt, err := tx.Prepare("INSERT INTO notes_fts (id, title, content) VALUES (?, ?, ?)")
+	stmt, err := tx.Prepare(insertNoteSQL)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	for _, n := range notes {
-		if _, err := stmt.Exec(n.ID, n.Title, n.Content); err != nil {
+	for i, n := range notes {
+		if _, err := stmt.Exec(noteRow(n)...); err != nil {
 			log.Printf("Failed to index note %s: %v", n.ID, err)
+			continue
+		}
+		if err := upsertState(tx, n.ID, 0, n.Version); err != nil {
+			return err
+		}
+		if err := writeLinks(tx, n); err != nil {
+			return err
+		}
+		if err := writeTasks(tx, n); err != nil {
+			return err
+		}
+		if err := writeSnippets(tx, n); err != nil {
+			return err
+		}
+		if (i+1)%progressEvery == 0 {
+			log.Printf("Reindexing: %d/%d notes", i+1, len(notes))
 		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.invalidateVocab()
+	return nil
 }
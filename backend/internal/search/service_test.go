@@ -0,0 +1,178 @@
+package search
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"marko-backend/internal/filesystem"
+	"marko-backend/internal/models"
+)
+
+// newTestService opens a service in a temp dir. The FTS5 module is only
+// compiled into go-sqlite3 with -tags sqlite_fts5, so tests skip without it.
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	s, err := NewService(t.TempDir(), Options{})
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			t.Skip("FTS5 not available; run tests with -tags sqlite_fts5")
+		}
+		t.Fatalf("NewService failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// indexFiles parses and indexes notes given as ID -> file content.
+func indexFiles(t *testing.T, s *Service, files map[string]string) {
+	t.Helper()
+
+	for id, content := range files {
+		if err := s.Index(filesystem.ParseNoteContent(id, []byte(content), time.Now())); err != nil {
+			t.Fatalf("Index %s: %v", id, err)
+		}
+	}
+}
+
+func TestCompileQuery(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"channels", "channels"},
+		{`"go channels" OR mutex`, `"go channels" OR mutex`},
+		{"tag:go", `tag_terms : "go"`},
+		{"tag:#Go-Lang", `tag_terms : "goz2dzlang"`},
+		{"tag:go author:alice channels", `tag_terms : "go" AND metadata : "author alice" AND channels`},
+		{`title:"go channels"`, `title : "go channels"`},
+		{"status:draft", `metadata : "status draft"`},
+		{"see http://example.com", `see "http://example.com"`},
+		{"kube*", "kube*"},
+		{`"go chan`, `"go chan"`},
+		{`"go chan"* -x`, `"go chan"* "-x"`},
+		{"(a OR", "(a)"},
+		{"a OR OR b)", "a OR b"},
+		{"NOT a AND", "a"},
+		{"a (b OR c) d", "a AND (b OR c) AND d"},
+		{"a () b", "a b"},
+		{"AND", ""},
+		{`tag:go "`, `tag_terms : "go"`},
+	}
+	for _, tt := range tests {
+		if got := compileQuery(tt.in); got != tt.want {
+			t.Errorf("compileQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSearch_FieldScoped(t *testing.T) {
+	s := newTestService(t)
+
+	notes := []models.Note{
+		{ID: "go.md", Title: "Go", Content: "channels and goroutines", Tags: []string{"go"}, Author: "Alice"},
+		{ID: "rust.md", Title: "Rust", Content: "channels and ownership", Tags: []string{"rust"}, Author: "Bob",
+			Metadata: map[string]any{"status": "draft"}},
+		{ID: "golang.md", Title: "Go lang", Content: "a tutorial", Tags: []string{"go-lang"}},
+	}
+	for _, n := range notes {
+		if err := s.Index(n); err != nil {
+			t.Fatalf("Index: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"channels", []string{"go.md", "rust.md"}},
+		{"tag:go channels", []string{"go.md"}},
+		{"author:bob", []string{"rust.md"}},
+		{"tag:go author:bob", nil},
+		{"status:draft", []string{"rust.md"}},
+		{"title:rust", []string{"rust.md"}},
+		{"tag:go-lang", []string{"golang.md"}},
+		{"tag:lang", nil},
+	}
+	for _, tt := range tests {
+		results, err := s.Search(tt.query)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.query, err)
+		}
+		var ids []string
+		for _, r := range results {
+			ids = append(ids, r.ID)
+		}
+		sort.Strings(ids)
+		if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+
+	results, _ := s.Search("tag:go")
+	if len(results) != 1 || len(results[0].Tags) != 1 || results[0].Tags[0] != "go" {
+		t.Errorf("expected tags on result, got %+v", results)
+	}
+}
+
+func TestSearchPage(t *testing.T) {
+	s := newTestService(t)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	titles := []string{"Delta", "alpha", "Charlie", "bravo", "Echo"}
+	for i, title := range titles {
+		n := models.Note{
+			ID:        strings.ToLower(title) + ".md",
+			Title:     title,
+			Content:   "shared term",
+			UpdatedAt: base.Add(time.Duration(i) * time.Hour),
+		}
+		if err := s.Index(n); err != nil {
+			t.Fatalf("Index: %v", err)
+		}
+	}
+
+	// Walk all pages by cursor.
+	var seen []string
+	opts := SearchOptions{Limit: 2, Sort: SortTitle}
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("cursor did not terminate")
+		}
+		page, err := s.SearchPage("shared", opts)
+		if err != nil {
+			t.Fatalf("SearchPage: %v", err)
+		}
+		if page.Total != 5 {
+			t.Errorf("Total = %d, want 5", page.Total)
+		}
+		for _, n := range page.Results {
+			seen = append(seen, n.Title)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	if got := strings.Join(seen, ","); got != "alpha,bravo,Charlie,Delta,Echo" {
+		t.Errorf("title order = %s", got)
+	}
+
+	page, err := s.SearchPage("shared", SearchOptions{Sort: SortUpdatedAt, Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("SearchPage: %v", err)
+	}
+	if len(page.Results) != 1 || page.Results[0].Title != "bravo" {
+		t.Errorf("updatedAt offset 1 = %+v, want bravo", page.Results)
+	}
+	if !page.Results[0].UpdatedAt.Equal(base.Add(3 * time.Hour)) {
+		t.Errorf("UpdatedAt = %v", page.Results[0].UpdatedAt)
+	}
+
+	if _, err := s.SearchPage("shared", SearchOptions{Sort: "size"}); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("expected ErrInvalidSort, got %v", err)
+	}
+	if _, err := s.SearchPage("shared", SearchOptions{Cursor: "bogus!"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
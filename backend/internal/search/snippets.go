@@ -0,0 +1,68 @@
+package search
+
+import (
+	"database/sql"
+	"strings"
+
+	"marko-backend/internal/models"
+)
+
+// writeSnippets replaces the rows of note in the snippets table.
+func writeSnippets(tx *sql.Tx, note models.Note) error {
+	if _, err := tx.Exec("DELETE FROM snippets WHERE id = ?", note.ID); err != nil {
+		return err
+	}
+	for _, sn := range note.Snippets {
+		if _, err := tx.Exec(`INSERT INTO snippets (id, title, line, lang, code, heading, anchor)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			note.ID, note.Title, sn.Line, sn.Lang, sn.Code, sn.Heading, sn.Anchor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SnippetFilter selects code snippets for Snippets. Zero values match
+// everything.
+type SnippetFilter struct {
+	Lang  string // language of the code block, any case
+	Query string // words that must all occur in the code or its heading
+}
+
+// Snippets returns the indexed code blocks matching f, ordered by note and
+// line. Query words are matched as case-insensitive substrings rather than
+// full-text tokens, so "fmt.Println" or "err !=" find what they say.
+func (s *Service) Snippets(f SnippetFilter) ([]models.Snippet, error) {
+	var where []string
+	var args []any
+	if f.Lang != "" {
+		where = append(where, "lang = ?")
+		args = append(args, strings.ToLower(f.Lang))
+	}
+	for _, word := range strings.Fields(f.Query) {
+		where = append(where, `(code LIKE ? ESCAPE '\' OR heading LIKE ? ESCAPE '\')`)
+		pattern := "%" + escapeLike(word) + "%"
+		args = append(args, pattern, pattern)
+	}
+	query := "SELECT id, title, line, lang, code, heading, anchor FROM snippets"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id, line"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Snippet{}
+	for rows.Next() {
+		var sn models.Snippet
+		if err := rows.Scan(&sn.NoteID, &sn.NoteTitle, &sn.Line, &sn.Lang, &sn.Code, &sn.Heading, &sn.Anchor); err != nil {
+			return nil, err
+		}
+		out = append(out, sn)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,57 @@
+package search
+
+import (
+	"testing"
+)
+
+func TestSnippets(t *testing.T) {
+	s := newTestService(t)
+
+	files := map[string]string{
+		"go/http.md":  "---\ntitle: HTTP\n---\n# Server\n\nListen with:\n\n```go\nhttp.ListenAndServe(\":8080\", nil)\n```\n",
+		"go/print.md": "# Printing\n\n## Code Snippet\n\n```Go\nfmt.Println(\"server started\")\n```\n\n```sh\ngo run .\n```\n",
+		"prose.md":    "# Prose\n\nA server without code.",
+	}
+	indexFiles(t, s, files)
+
+	all, err := s.Snippets(SnippetFilter{})
+	if err != nil {
+		t.Fatalf("Snippets: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 snippets, got %+v", all)
+	}
+	if sn := all[0]; sn.NoteID != "go/http.md" || sn.NoteTitle != "HTTP" || sn.Line != 8 || sn.Lang != "go" ||
+		sn.Heading != "Server" || sn.Anchor != "server" {
+		t.Errorf("unexpected snippet %+v", sn)
+	}
+
+	tests := []struct {
+		filter SnippetFilter
+		want   int
+	}{
+		{SnippetFilter{Lang: "GO"}, 2},
+		{SnippetFilter{Lang: "sh"}, 1},
+		{SnippetFilter{Query: "fmt.Println"}, 1},
+		{SnippetFilter{Query: "snippet run"}, 1}, // heading and code
+		{SnippetFilter{Lang: "go", Query: "%"}, 0},
+	}
+	for _, tt := range tests {
+		got, err := s.Snippets(tt.filter)
+		if err != nil || len(got) != tt.want {
+			t.Errorf("Snippets(%+v) = %d results, %v; want %d", tt.filter, len(got), err, tt.want)
+		}
+	}
+
+	// Code-only search skips notes that mention the word in prose only
+	page, err := s.SearchPage("server", SearchOptions{CodeOnly: true})
+	if err != nil {
+		t.Fatalf("SearchPage: %v", err)
+	}
+	if page.Total != 1 || page.Results[0].ID != "go/print.md" {
+		t.Errorf("code-only search = %+v", page.Results)
+	}
+	if page, _ := s.SearchPage("server", SearchOptions{}); page.Total != 3 {
+		t.Errorf("full search found %d notes, want 3", page.Total)
+	}
+}
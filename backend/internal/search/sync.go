@@ -0,0 +1,151 @@
+package search
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"time"
+
+	"marko-backend/internal/models"
+)
+
+// progressEvery controls how often long reindex runs log their progress.
+const progressEvery = 500
+
+// Source is the view of the note store needed to synchronize the index.
+// storage.NoteRepository satisfies it.
+type Source interface {
+	ListInfo() ([]models.NoteInfo, error)
+	Get(id string) (models.Note, error)
+}
+
+// SyncStats summarizes an incremental Sync.
+type SyncStats struct {
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+	Duration  time.Duration
+}
+
+// Sync brings the index up to date with source, touching only notes that
+// were added, changed or removed since they were last indexed. A note whose
+// mtime changed but whose content hash did not is not reindexed.
+func (s *Service) Sync(source Source) (SyncStats, error) {
+	start := time.Now()
+	var stats SyncStats
+
+	infos, err := source.ListInfo()
+	if err != nil {
+		return stats, err
+	}
+
+	type state struct {
+		mtime int64
+		hash  string
+	}
+	indexed := make(map[string]state)
+	rows, err := s.db.Query("SELECT id, mtime, hash FROM notes_state")
+	if err != nil {
+		return stats, err
+	}
+	for rows.Next() {
+		var id string
+		var st state
+		if err := rows.Scan(&id, &st.mtime, &st.hash); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		indexed[id] = st
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	for i, info := range infos {
+		if (i+1)%progressEvery == 0 {
+			log.Printf("Syncing search index: %d/%d notes checked", i+1, len(infos))
+		}
+
+		mtime := info.ModTime.UnixNano()
+		st, known := indexed[info.ID]
+		delete(indexed, info.ID)
+		if known && st.mtime == mtime {
+			stats.Unchanged++
+			continue
+		}
+
+		if err := s.syncNote(source, info.ID, mtime, st.hash, known, &stats); err != nil {
+			log.Printf("Failed to sync note %s: %v", info.ID, err)
+		}
+	}
+
+	// Whatever is left in the index no longer exists in the store
+	for id := range indexed {
+		s.mu.Lock()
+		err := s.delete(id)
+		s.mu.Unlock()
+		if err != nil {
+			return stats, err
+		}
+		stats.Removed++
+	}
+
+	// Drop rows indexed before state tracking existed that match no note
+	s.mu.Lock()
+	err = s.dropOrphans()
+	s.mu.Unlock()
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Duration = time.Since(start)
+	return stats, nil
+}
+
+// dropOrphans removes derived rows of notes that have no state, i.e. rows
+// indexed before state tracking existed. Callers must hold s.mu.
+func (s *Service) dropOrphans() error {
+	for _, table := range noteTables {
+		if table == "notes_state" {
+			continue
+		}
+		if _, err := s.db.Exec("DELETE FROM " + table + " WHERE id NOT IN (SELECT id FROM notes_state)"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) syncNote(source Source, id string, mtime int64, hash string, known bool, stats *SyncStats) error {
+	// Read under the lock so a concurrent handler Index cannot be
+	// overwritten with older content
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	note, err := source.Get(id)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil // Deleted while syncing; the handler removes it from the index
+	}
+	if err != nil {
+		return err
+	}
+
+	if known && note.Version == hash {
+		// Touched but unchanged: only remember the new mtime
+		stats.Unchanged++
+		_, err := s.db.Exec("UPDATE notes_state SET mtime = ? WHERE id = ?", mtime, id)
+		return err
+	}
+
+	if err := s.index(note, mtime); err != nil {
+		return err
+	}
+	if known {
+		stats.Updated++
+	} else {
+		stats.Added++
+	}
+	return nil
+}
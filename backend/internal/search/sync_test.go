@@ -0,0 +1,60 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"marko-backend/internal/filesystem"
+)
+
+func TestService_SyncIsIncremental(t *testing.T) {
+	s := newTestService(t)
+	dir := t.TempDir()
+	store := filesystem.NewStore(dir)
+
+	store.Save("one", "# One\n\nalpha")
+	store.Save("two", "# Two\n\nbeta")
+
+	stats, err := s.Sync(store)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if stats.Added != 2 || stats.Unchanged != 0 {
+		t.Errorf("unexpected first sync stats: %+v", stats)
+	}
+
+	// Touch one note without changing it, edit another, add and remove
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "one.md"), future, future)
+	store.Save("two", "# Two\n\ngamma")
+	store.Save("three", "# Three")
+	store.Delete("one.md")
+	store.Save("one", "# One\n\nalpha")
+
+	stats, err = s.Sync(store)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if stats.Added != 1 || stats.Updated != 1 || stats.Unchanged != 1 || stats.Removed != 0 {
+		t.Errorf("unexpected second sync stats: %+v", stats)
+	}
+
+	store.Delete("three.md")
+	stats, err = s.Sync(store)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if stats.Removed != 1 || stats.Unchanged != 2 {
+		t.Errorf("unexpected third sync stats: %+v", stats)
+	}
+
+	results, err := s.Search("gamma")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "two.md" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
@@ -0,0 +1,94 @@
+package search
+
+import (
+	"database/sql"
+	"strings"
+
+	"marko-backend/internal/models"
+)
+
+// writeTasks replaces the rows of note in the tasks table.
+func writeTasks(tx *sql.Tx, note models.Note) error {
+	if _, err := tx.Exec("DELETE FROM tasks WHERE id = ?", note.ID); err != nil {
+		return err
+	}
+	for _, t := range note.Tasks {
+		if _, err := tx.Exec(`INSERT INTO tasks (id, title, line, text, done, due, priority, tags, match_tags)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			note.ID, note.Title, t.Line, t.Text, t.Done, t.Due, t.Priority,
+			strings.Join(t.Tags, " "), matchTags(t.Tags, note.Tags)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchTags joins the lower-cased tags of a task and its note as
+// "|a|b|", so a tag filter is a LIKE on "%|tag|%".
+func matchTags(lists ...[]string) string {
+	var sb strings.Builder
+	sb.WriteString("|")
+	for _, tags := range lists {
+		for _, t := range tags {
+			sb.WriteString(strings.ToLower(t) + "|")
+		}
+	}
+	return sb.String()
+}
+
+// TaskFilter selects tasks for Tasks. Zero values match everything.
+type TaskFilter struct {
+	Done      *bool  // only done (true) or open (false) tasks
+	HasDue    bool   // only tasks with a due date
+	DueBefore string // "YYYY-MM-DD": only tasks due before this day
+	Tag       string // tag of the task or its note, any case
+}
+
+// Tasks returns the indexed tasks matching f, those with a due date first
+// (earliest first), then by note and line.
+func (s *Service) Tasks(f TaskFilter) ([]models.Task, error) {
+	var where []string
+	var args []any
+	if f.Done != nil {
+		where = append(where, "done = ?")
+		args = append(args, *f.Done)
+	}
+	if f.HasDue || f.DueBefore != "" {
+		where = append(where, "due != ''")
+	}
+	if f.DueBefore != "" {
+		where = append(where, "due < ?")
+		args = append(args, f.DueBefore)
+	}
+	if f.Tag != "" {
+		where = append(where, "match_tags LIKE ? ESCAPE '\\'")
+		args = append(args, "%|"+escapeLike(strings.ToLower(f.Tag))+"|%")
+	}
+	query := "SELECT id, title, line, text, done, due, priority, tags FROM tasks"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY due = '', due, id, line"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Task{}
+	for rows.Next() {
+		var t models.Task
+		var tags string
+		if err := rows.Scan(&t.NoteID, &t.NoteTitle, &t.Line, &t.Text, &t.Done, &t.Due, &t.Priority, &tags); err != nil {
+			return nil, err
+		}
+		t.Tags = strings.Fields(tags)
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(s string) string { return likeEscaper.Replace(s) }
@@ -0,0 +1,58 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTasks(t *testing.T) {
+	s := newTestService(t)
+
+	files := map[string]string{
+		"trip.md": "---\ntitle: Trip\ntags: [travel]\n---\n- [ ] book flights @2026-11-01 !high\n- [x] renew passport @2026-10-01\n- [ ] pack",
+		"work.md": "# Work\n\n- [ ] review PR @2026-10-15 #code\n- [x] deploy #code_review",
+	}
+	indexFiles(t, s, files)
+
+	open, done := false, true
+	tests := []struct {
+		name   string
+		filter TaskFilter
+		want   []string
+	}{
+		{"all", TaskFilter{}, []string{"renew passport @2026-10-01", "review PR @2026-10-15 #code", "book flights @2026-11-01 !high", "pack", "deploy #code_review"}},
+		{"open", TaskFilter{Done: &open}, []string{"review PR @2026-10-15 #code", "book flights @2026-11-01 !high", "pack"}},
+		{"done", TaskFilter{Done: &done}, []string{"renew passport @2026-10-01", "deploy #code_review"}},
+		{"due", TaskFilter{HasDue: true}, []string{"renew passport @2026-10-01", "review PR @2026-10-15 #code", "book flights @2026-11-01 !high"}},
+		{"overdue", TaskFilter{Done: &open, DueBefore: "2026-10-20"}, []string{"review PR @2026-10-15 #code"}},
+		{"note tag", TaskFilter{Tag: "Travel"}, []string{"renew passport @2026-10-01", "book flights @2026-11-01 !high", "pack"}},
+		{"task tag", TaskFilter{Tag: "code"}, []string{"review PR @2026-10-15 #code"}},
+		{"like wildcards", TaskFilter{Tag: "code_"}, []string{}},
+	}
+	for _, tt := range tests {
+		tasks, err := s.Tasks(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: Tasks: %v", tt.name, err)
+		}
+		got := []string{}
+		for _, task := range tasks {
+			got = append(got, task.Text)
+		}
+		if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	tasks, _ := s.Tasks(TaskFilter{Tag: "code"})
+	if len(tasks) == 1 && (tasks[0].NoteID != "work.md" || tasks[0].NoteTitle != "Work" || tasks[0].Line != 3) {
+		t.Errorf("unexpected task %+v", tasks[0])
+	}
+
+	// Deleting a note drops its tasks
+	if err := s.Delete("trip.md"); err != nil {
+		t.Fatal(err)
+	}
+	if tasks, _ := s.Tasks(TaskFilter{}); len(tasks) != 2 {
+		t.Errorf("expected 2 tasks after delete, got %+v", tasks)
+	}
+}
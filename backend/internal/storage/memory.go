@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"marko-backend/internal/filesystem"
+	"marko-backend/internal/models"
+)
+
+type memoryNote struct {
+	content string
+	modTime time.Time
+}
+
+// MemoryRepository keeps notes in memory. It is intended for tests and
+// throwaway deployments; nothing survives a restart.
+type MemoryRepository struct {
+	mu    sync.RWMutex
+	notes map[string]memoryNote
+}
+
+var _ NoteRepository = (*MemoryRepository)(nil)
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{notes: make(map[string]memoryNote)}
+}
+
+func (m *MemoryRepository) List() ([]models.Note, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	notes := []models.Note{}
+	for id, n := range m.notes {
+		notes = append(notes, filesystem.ListEntry(filesystem.ParseNoteContent(id, []byte(n.content), n.modTime)))
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+	return notes, nil
+}
+
+func (m *MemoryRepository) Get(id string) (models.Note, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n, ok := m.notes[canonicalID(id)]
+	if !ok {
+		return models.Note{}, notFound(id)
+	}
+	return filesystem.ParseNoteContent(id, []byte(n.content), n.modTime), nil
+}
+
+func (m *MemoryRepository) Raw(id string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n, ok := m.notes[canonicalID(id)]
+	if !ok {
+		return nil, notFound(id)
+	}
+	return []byte(n.content), nil
+}
+
+func (m *MemoryRepository) Save(id string, content string) error {
+	if id == "" {
+		return fmt.Errorf("id required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.notes[canonicalID(id)] = memoryNote{content: content, modTime: time.Now()}
+	return nil
+}
+
+func (m *MemoryRepository) SaveIfMatch(id, content, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkVersion(id, version); err != nil {
+		return err
+	}
+	m.notes[canonicalID(id)] = memoryNote{content: content, modTime: time.Now()}
+	return nil
+}
+
+func (m *MemoryRepository) DeleteIfMatch(id, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkVersion(id, version); err != nil {
+		return err
+	}
+	delete(m.notes, canonicalID(id))
+	return nil
+}
+
+// checkVersion must be called with m.mu held.
+func (m *MemoryRepository) checkVersion(id, version string) error {
+	n, ok := m.notes[canonicalID(id)]
+	if !ok {
+		return notFound(id)
+	}
+	if filesystem.ContentVersion([]byte(n.content)) != version {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (m *MemoryRepository) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := canonicalID(id)
+	if _, ok := m.notes[key]; !ok {
+		return notFound(id)
+	}
+	delete(m.notes, key)
+	return nil
+}
+
+func (m *MemoryRepository) Rename(oldID, newID string) error {
+	if newID == "" {
+		return fmt.Errorf("id required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldKey, newKey := canonicalID(oldID), canonicalID(newID)
+	n, ok := m.notes[oldKey]
+	if !ok {
+		return notFound(oldID)
+	}
+	if _, exists := m.notes[newKey]; exists {
+		return fmt.Errorf("note %q: %w", newKey, ErrExists)
+	}
+	delete(m.notes, oldKey)
+	m.notes[newKey] = n
+	return nil
+}
+
+func (m *MemoryRepository) Stat(id string) (models.NoteInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key := canonicalID(id)
+	n, ok := m.notes[key]
+	if !ok {
+		return models.NoteInfo{}, notFound(id)
+	}
+	return models.NoteInfo{ID: key, Size: int64(len(n.content)), ModTime: n.modTime}, nil
+}
+
+func (m *MemoryRepository) ListInfo() ([]models.NoteInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infos := []models.NoteInfo{}
+	for id, n := range m.notes {
+		infos = append(infos, models.NoteInfo{ID: id, Size: int64(len(n.content)), ModTime: n.modTime})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos, nil
+}
+
+// notFound wraps os.ErrNotExist so callers can treat every backend alike.
+func notFound(id string) error {
+	return fmt.Errorf("note %q: %w", id, os.ErrNotExist)
+}
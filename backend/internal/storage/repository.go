@@ -0,0 +1,97 @@
+// Package storage defines the NoteRepository abstraction used by the HTTP
+// handlers, along with alternative backends to the on-disk filesystem.Store.
+package storage
+
+import (
+	"strings"
+	"time"
+
+	"marko-backend/internal/filesystem"
+	"marko-backend/internal/gitvault"
+	"marko-backend/internal/models"
+)
+
+// NoteRepository is the persistence contract for notes.
+//
+// IDs follow the filesystem.Store conventions: a note saved as "foo" is listed
+// as "foo.md", and Get/Delete accept either form. Missing notes are reported
+// with errors satisfying errors.Is(err, fs.ErrNotExist).
+type NoteRepository interface {
+	List() ([]models.Note, error)
+	Get(id string) (models.Note, error)
+	Raw(id string) ([]byte, error) // Unparsed file content, frontmatter included
+	Save(id string, content string) error
+	Delete(id string) error
+	Rename(oldID, newID string) error
+
+	// SaveIfMatch and DeleteIfMatch fail with ErrVersionConflict unless the
+	// stored note's Version equals version, for optimistic concurrency.
+	SaveIfMatch(id, content, version string) error
+	DeleteIfMatch(id, version string) error
+
+	// Stat and ListInfo return file-level metadata without parsing content.
+	Stat(id string) (models.NoteInfo, error)
+	ListInfo() ([]models.NoteInfo, error)
+}
+
+var _ NoteRepository = (*filesystem.Store)(nil)
+
+// ErrVersionConflict is returned by conditional writes when the stored note
+// no longer matches the version the caller expected.
+var ErrVersionConflict = filesystem.ErrVersionConflict
+
+// ErrExists is returned when a rename or restore would overwrite a note.
+var ErrExists = filesystem.ErrExists
+
+// canonicalID normalizes an ID to the ".md" form used as the storage key.
+func canonicalID(id string) string {
+	if !strings.HasSuffix(id, ".md") {
+		return id + ".md"
+	}
+	return id
+}
+
+// FolderRepository is implemented by backends that organize notes in nested
+// folders. Folder paths are slash-separated and relative to the vault root.
+type FolderRepository interface {
+	Folders() (models.Folder, error)
+	CreateFolder(folder string) error
+	RenameFolder(oldFolder, newFolder string) error
+	DeleteFolder(folder string, recursive bool) error
+	NotesIn(folder string) ([]models.NoteInfo, error)
+}
+
+var _ FolderRepository = (*filesystem.Store)(nil)
+
+// RevisionRepository is implemented by backends that keep a history of
+// previous note contents.
+type RevisionRepository interface {
+	Revisions(id string) ([]models.Revision, error)
+	GetRevision(id, rev string) (string, error)
+}
+
+var _ RevisionRepository = (*filesystem.Store)(nil)
+
+// TrashRepository is implemented by backends whose Delete moves notes to a
+// trash instead of removing them. Trash item IDs are opaque to callers.
+type TrashRepository interface {
+	Trash() ([]models.TrashItem, error)
+	RestoreTrash(id string) (string, error)
+	PurgeTrash(id string) error
+	EmptyTrash(cutoff time.Time) (int, error)
+}
+
+var _ TrashRepository = (*filesystem.Store)(nil)
+
+// CommitLogRepository is implemented by version-controlled backends that can
+// report which commits touched a note.
+type CommitLogRepository interface {
+	Log(id string) ([]models.Commit, error)
+}
+
+var (
+	_ NoteRepository      = (*gitvault.Store)(nil)
+	_ FolderRepository    = (*gitvault.Store)(nil)
+	_ CommitLogRepository = (*gitvault.Store)(nil)
+	_ TrashRepository     = (*gitvault.Store)(nil)
+)
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"marko-backend/internal/filesystem"
+)
+
+// testRepository exercises the NoteRepository contract shared by every backend.
+func testRepository(t *testing.T, repo NoteRepository) {
+	if err := repo.Save("first", "---\ntitle: First\n---\n# Body\n\n- [ ] read [[second]]"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	note, err := repo.Get("first")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if note.Title != "First" || note.Content != "# Body\n\n- [ ] read [[second]]" {
+		t.Errorf("unexpected note: %+v", note)
+	}
+
+	notes, err := repo.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(notes) != 1 || notes[0].ID != "first.md" || notes[0].Content != "" {
+		t.Errorf("unexpected list: %+v", notes)
+	}
+	// Lists have the same shape whatever the backend
+	if len(notes) == 1 && (notes[0].Version != "" || notes[0].Links != nil || notes[0].Outline != nil || notes[0].Tasks != nil) {
+		t.Errorf("list entry has fields only Get serves: %+v", notes[0])
+	}
+
+	info, err := repo.Stat("first.md")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.ID != "first.md" || info.Size == 0 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	if err := repo.SaveIfMatch("first", "---\ntitle: First\n---\nEdited", note.Version); err != nil {
+		t.Fatalf("SaveIfMatch with current version failed: %v", err)
+	}
+	if err := repo.SaveIfMatch("first", "stale write", note.Version); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected version conflict, got %v", err)
+	}
+	if err := repo.DeleteIfMatch("first", note.Version); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("expected version conflict on delete, got %v", err)
+	}
+	if edited, _ := repo.Get("first"); edited.Content != "Edited" || edited.Version == note.Version {
+		t.Errorf("unexpected note after conditional save: %+v", edited)
+	}
+
+	if err := repo.Save("second", "# Second"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := repo.Rename("first", "second"); err == nil {
+		t.Error("Rename onto an existing note should fail")
+	}
+	if err := repo.Rename("first", "renamed"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if _, err := repo.Get("first"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not-exist after rename, got %v", err)
+	}
+
+	infos, err := repo.ListInfo()
+	if err != nil {
+		t.Fatalf("ListInfo failed: %v", err)
+	}
+	if len(infos) != 2 || infos[0].ID != "renamed.md" || infos[1].ID != "second.md" {
+		t.Errorf("unexpected infos: %+v", infos)
+	}
+
+	if err := repo.Delete("renamed.md"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := repo.Delete("renamed.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not-exist on second delete, got %v", err)
+	}
+}
+
+func TestFilesystemStore(t *testing.T) {
+	testRepository(t, filesystem.NewStore(t.TempDir()))
+}
+
+func TestMemoryRepository(t *testing.T) {
+	testRepository(t, NewMemoryRepository())
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	repo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "notes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	testRepository(t, repo)
+}
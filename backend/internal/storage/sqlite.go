@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"marko-backend/internal/filesystem"
+	"marko-backend/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteRepository stores notes as rows in a single SQLite database file.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+var _ NoteRepository = (*SQLiteRepository)(nil)
+
+func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &SQLiteRepository{db: db}
+	if err := r.initSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *SQLiteRepository) initSchema() error {
+	_, err := r.db.Exec(`
+	CREATE TABLE IF NOT EXISTS notes (
+		id TEXT PRIMARY KEY,
+		content TEXT NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+	`)
+	return err
+}
+
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *SQLiteRepository) List() ([]models.Note, error) {
+	rows, err := r.db.Query("SELECT id, content, updated_at FROM notes ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []models.Note{}
+	for rows.Next() {
+		var id, content string
+		var updated int64
+		if err := rows.Scan(&id, &content, &updated); err != nil {
+			return nil, err
+		}
+		notes = append(notes, filesystem.ListEntry(filesystem.ParseNoteContent(id, []byte(content), time.Unix(0, updated))))
+	}
+	return notes, rows.Err()
+}
+
+func (r *SQLiteRepository) Get(id string) (models.Note, error) {
+	var content string
+	var updated int64
+	err := r.db.QueryRow("SELECT content, updated_at FROM notes WHERE id = ?", canonicalID(id)).Scan(&content, &updated)
+	if err == sql.ErrNoRows {
+		return models.Note{}, notFound(id)
+	}
+	if err != nil {
+		return models.Note{}, err
+	}
+	return filesystem.ParseNoteContent(id, []byte(content), time.Unix(0, updated)), nil
+}
+
+func (r *SQLiteRepository) Raw(id string) ([]byte, error) {
+	var content string
+	err := r.db.QueryRow("SELECT content FROM notes WHERE id = ?", canonicalID(id)).Scan(&content)
+	if err == sql.ErrNoRows {
+		return nil, notFound(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+func (r *SQLiteRepository) Save(id string, content string) error {
+	if id == "" {
+		return fmt.Errorf("id required")
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO notes (id, content, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET content = excluded.content, updated_at = excluded.updated_at`,
+		canonicalID(id), content, time.Now().UnixNano())
+	return err
+}
+
+func (r *SQLiteRepository) SaveIfMatch(id, content, version string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkVersion(tx, id, version); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE notes SET content = ?, updated_at = ? WHERE id = ?",
+		content, time.Now().UnixNano(), canonicalID(id)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteRepository) DeleteIfMatch(id, version string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkVersion(tx, id, version); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM notes WHERE id = ?", canonicalID(id)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func checkVersion(tx *sql.Tx, id, version string) error {
+	var content string
+	err := tx.QueryRow("SELECT content FROM notes WHERE id = ?", canonicalID(id)).Scan(&content)
+	if err == sql.ErrNoRows {
+		return notFound(id)
+	}
+	if err != nil {
+		return err
+	}
+	if filesystem.ContentVersion([]byte(content)) != version {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Delete(id string) error {
+	res, err := r.db.Exec("DELETE FROM notes WHERE id = ?", canonicalID(id))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return notFound(id)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Rename(oldID, newID string) error {
+	if newID == "" {
+		return fmt.Errorf("id required")
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM notes WHERE id = ?", canonicalID(newID)).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return fmt.Errorf("note %q: %w", canonicalID(newID), ErrExists)
+	}
+
+	res, err := tx.Exec("UPDATE notes SET id = ? WHERE id = ?", canonicalID(newID), canonicalID(oldID))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return notFound(oldID)
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteRepository) Stat(id string) (models.NoteInfo, error) {
+	info := models.NoteInfo{ID: canonicalID(id)}
+	var updated int64
+	err := r.db.QueryRow("SELECT length(CAST(content AS BLOB)), updated_at FROM notes WHERE id = ?", info.ID).Scan(&info.Size, &updated)
+	if err == sql.ErrNoRows {
+		return models.NoteInfo{}, notFound(id)
+	}
+	if err != nil {
+		return models.NoteInfo{}, err
+	}
+	info.ModTime = time.Unix(0, updated)
+	return info, nil
+}
+
+func (r *SQLiteRepository) ListInfo() ([]models.NoteInfo, error) {
+	rows, err := r.db.Query("SELECT id, length(CAST(content AS BLOB)), updated_at FROM notes ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	infos := []models.NoteInfo{}
+	for rows.Next() {
+		var info models.NoteInfo
+		var updated int64
+		if err := rows.Scan(&info.ID, &info.Size, &updated); err != nil {
+			return nil, err
+		}
+		info.ModTime = time.Unix(0, updated)
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
+}
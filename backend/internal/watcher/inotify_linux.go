@@ -0,0 +1,199 @@
+//go:build linux
+
+package watcher
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const watchMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_ONLYDIR
+
+// inotifyBackend watches every directory of the vault with inotify.
+// inotify is not recursive, so new directories get their own watch as they
+// appear.
+type inotifyBackend struct {
+	dir  string
+	emit func(Event)
+	fd   int
+	file *os.File // Wraps fd in the runtime poller, so Close interrupts Read
+
+	mu      sync.Mutex
+	watches map[int32]string // Watch descriptor -> relative dir ("" for root)
+	done    chan struct{}
+}
+
+func newNativeBackend(dir string, emit func(Event)) (backend, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	b := &inotifyBackend{
+		dir:     dir,
+		emit:    emit,
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		watches: make(map[int32]string),
+		done:    make(chan struct{}),
+	}
+	if err := b.addTree(""); err != nil {
+		b.file.Close()
+		return nil, err
+	}
+
+	go b.readLoop()
+	return b, nil
+}
+
+// addTree watches rel and every non-hidden directory below it. Watching a
+// directory that is already watched (e.g. after a rename) returns the same
+// descriptor, which is then mapped to its new path.
+func (b *inotifyBackend) addTree(rel string) error {
+	root := filepath.Join(b.dir, filepath.FromSlash(rel))
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != b.dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		wd, err := syscall.InotifyAddWatch(b.fd, path, watchMask)
+		if err != nil {
+			return os.NewSyscallError("inotify_add_watch", err)
+		}
+		relPath, _ := filepath.Rel(b.dir, path)
+		if relPath == "." {
+			relPath = ""
+		}
+
+		b.mu.Lock()
+		b.watches[int32(wd)] = filepath.ToSlash(relPath)
+		b.mu.Unlock()
+		return nil
+	})
+}
+
+func (b *inotifyBackend) readLoop() {
+	defer close(b.done)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := b.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				log.Printf("Watcher: inotify read failed: %v", err)
+			}
+			return
+		}
+		b.process(buf[:n])
+	}
+}
+
+// process decodes one read's worth of events. MOVED_FROM/MOVED_TO pairs
+// sharing a cookie within the batch become renames; an unpaired MOVED_FROM
+// means the file left the vault.
+func (b *inotifyBackend) process(buf []byte) {
+	movedFrom := make(map[uint32]Event)
+	var order []uint32
+
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(raw.Len)]
+		offset += syscall.SizeofInotifyEvent + int(raw.Len)
+
+		name := string(bytes.TrimRight(nameBytes, "\x00"))
+		mask := raw.Mask
+
+		b.mu.Lock()
+		dir, known := b.watches[raw.Wd]
+		if mask&syscall.IN_IGNORED != 0 {
+			delete(b.watches, raw.Wd)
+		}
+		b.mu.Unlock()
+		if !known || name == "" {
+			continue
+		}
+
+		id := name
+		if dir != "" {
+			id = dir + "/" + name
+		}
+		isDir := mask&syscall.IN_ISDIR != 0
+		if !isDir && !isNote(name) {
+			continue
+		}
+
+		switch {
+		case mask&syscall.IN_MOVED_FROM != 0:
+			movedFrom[raw.Cookie] = Event{ID: id, Op: Remove, IsDir: isDir}
+			order = append(order, raw.Cookie)
+		case mask&syscall.IN_MOVED_TO != 0:
+			if isDir {
+				b.addTree(id)
+			}
+			if from, ok := movedFrom[raw.Cookie]; ok {
+				delete(movedFrom, raw.Cookie)
+				b.emit(Event{ID: id, OldID: from.ID, Op: Rename, IsDir: isDir})
+			} else {
+				b.emit(Event{ID: id, Op: Create, IsDir: isDir})
+			}
+		case mask&syscall.IN_CREATE != 0:
+			if isDir {
+				// Files may land in the new folder before its watch exists
+				b.addTree(id)
+				b.emit(Event{ID: id, Op: Create, IsDir: true})
+			} else {
+				b.emit(Event{ID: id, Op: Create})
+			}
+		case mask&syscall.IN_CLOSE_WRITE != 0:
+			b.emit(Event{ID: id, Op: Write})
+		case mask&syscall.IN_DELETE != 0:
+			b.emit(Event{ID: id, Op: Remove, IsDir: isDir})
+		}
+	}
+
+	for _, cookie := range order {
+		if ev, ok := movedFrom[cookie]; ok {
+			if ev.IsDir {
+				b.removeTree(ev.ID)
+			}
+			b.emit(ev)
+		}
+	}
+}
+
+// removeTree drops the watches of a directory that left the vault.
+func (b *inotifyBackend) removeTree(rel string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for wd, dir := range b.watches {
+		if dir == rel || strings.HasPrefix(dir, rel+"/") {
+			syscall.InotifyRmWatch(b.fd, uint32(wd))
+			delete(b.watches, wd)
+		}
+	}
+}
+
+func (b *inotifyBackend) Close() error {
+	err := b.file.Close()
+	<-b.done
+	return err
+}
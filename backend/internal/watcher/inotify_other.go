@@ -0,0 +1,10 @@
+//go:build !linux
+
+package watcher
+
+import "errors"
+
+// newNativeBackend is only implemented on Linux; other platforms poll.
+func newNativeBackend(dir string, emit func(Event)) (backend, error) {
+	return nil, errors.New("native file watching is not supported on this platform")
+}
@@ -0,0 +1,117 @@
+package watcher
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// pollBackend detects changes by periodically scanning the vault and
+// comparing modification times and sizes. It cannot tell renames apart from
+// a removal plus a creation.
+type pollBackend struct {
+	dir    string
+	emit   func(Event)
+	state  map[string]fileState
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	closer sync.Once
+}
+
+func newPollBackend(dir string, interval time.Duration, emit func(Event)) (*pollBackend, error) {
+	p := &pollBackend{dir: dir, emit: emit, stop: make(chan struct{})}
+
+	state, err := p.scan()
+	if err != nil {
+		return nil, err
+	}
+	p.state = state
+
+	p.wg.Add(1)
+	go p.loop(interval)
+	return p, nil
+}
+
+func (p *pollBackend) loop(interval time.Duration) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.poll()
+		}
+	}
+}
+
+func (p *pollBackend) poll() {
+	current, err := p.scan()
+	if err != nil {
+		return
+	}
+
+	for id, st := range current {
+		prev, ok := p.state[id]
+		switch {
+		case !ok:
+			p.emit(Event{ID: id, Op: Create})
+		case !prev.modTime.Equal(st.modTime) || prev.size != st.size:
+			p.emit(Event{ID: id, Op: Write})
+		}
+	}
+	for id := range p.state {
+		if _, ok := current[id]; !ok {
+			p.emit(Event{ID: id, Op: Remove})
+		}
+	}
+	p.state = current
+}
+
+func (p *pollBackend) scan() (map[string]fileState, error) {
+	state := make(map[string]fileState)
+	err := filepath.WalkDir(p.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == p.dir {
+				return err
+			}
+			return nil
+		}
+		if path != p.dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isNote(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(p.dir, path)
+		if err != nil {
+			return nil
+		}
+		state[filepath.ToSlash(rel)] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return state, err
+}
+
+func (p *pollBackend) Close() error {
+	p.closer.Do(func() { close(p.stop) })
+	p.wg.Wait()
+	return nil
+}
@@ -0,0 +1,261 @@
+// Package watcher keeps the search index in sync with edits made to the
+// vault outside of the HTTP API (editors, git checkouts, sync tools).
+package watcher
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"marko-backend/internal/models"
+)
+
+// Op describes what happened to a note file.
+type Op int
+
+const (
+	Create Op = iota
+	Write
+	Remove
+	Rename
+)
+
+func (op Op) String() string {
+	switch op {
+	case Create:
+		return "create"
+	case Write:
+		return "write"
+	case Remove:
+		return "remove"
+	case Rename:
+		return "rename"
+	}
+	return "unknown"
+}
+
+// Event is a change to a note (or, with IsDir, to a whole folder).
+// IDs are slash-separated paths relative to the vault root.
+type Event struct {
+	ID    string
+	OldID string // Previous ID for renames
+	Op    Op
+	IsDir bool
+}
+
+// Indexer receives the changes detected by the watcher.
+// search.Service satisfies it.
+type Indexer interface {
+	Index(note models.Note) error
+	Delete(id string) error
+}
+
+// PrefixDeleter is optionally implemented by an Indexer to drop every note
+// under a folder that was removed or moved away as a whole.
+type PrefixDeleter interface {
+	DeletePrefix(prefix string) error
+}
+
+// Source reads notes back after they change. storage.NoteRepository satisfies it.
+type Source interface {
+	Get(id string) (models.Note, error)
+	NotesIn(folder string) ([]models.NoteInfo, error)
+}
+
+// Options tunes the watcher. Zero values select sensible defaults.
+type Options struct {
+	// Debounce is how long the watcher waits for a burst of events to
+	// settle before touching the index.
+	Debounce time.Duration
+
+	// PollInterval is the scan interval of the polling backend.
+	PollInterval time.Duration
+
+	// ForcePolling skips the native (inotify) backend.
+	ForcePolling bool
+}
+
+// backend produces events for a directory tree until closed.
+type backend interface {
+	Close() error
+}
+
+// Watcher debounces filesystem events and applies them to an Indexer.
+type Watcher struct {
+	dir     string
+	source  Source
+	indexer Indexer
+	opts    Options
+
+	backend backend
+
+	mu      sync.Mutex
+	pending map[string]Event
+	timer   *time.Timer
+	closed  bool
+}
+
+func New(dir string, source Source, indexer Indexer, opts Options) *Watcher {
+	if opts.Debounce <= 0 {
+		opts.Debounce = 250 * time.Millisecond
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	return &Watcher{
+		dir:     dir,
+		source:  source,
+		indexer: indexer,
+		opts:    opts,
+		pending: make(map[string]Event),
+	}
+}
+
+// Start begins watching. It uses inotify where available and falls back to
+// polling otherwise. The returned string names the backend in use.
+func (w *Watcher) Start() (string, error) {
+	if !w.opts.ForcePolling {
+		b, err := newNativeBackend(w.dir, w.handle)
+		if err == nil {
+			w.backend = b
+			return "inotify", nil
+		}
+		log.Printf("Watcher: native backend unavailable, polling instead: %v", err)
+	}
+
+	b, err := newPollBackend(w.dir, w.opts.PollInterval, w.handle)
+	if err != nil {
+		return "", err
+	}
+	w.backend = b
+	return "polling", nil
+}
+
+// Close stops the watcher and applies any pending events.
+func (w *Watcher) Close() error {
+	var err error
+	if w.backend != nil {
+		err = w.backend.Close()
+	}
+
+	w.mu.Lock()
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+
+	w.flush()
+	return err
+}
+
+// handle queues an event. Events for the same ID within the debounce window
+// collapse into one.
+func (w *Watcher) handle(ev Event) {
+	if ev.Op == Rename {
+		// Moves into or out of hidden folders (.trash, editor temp files)
+		// are plain removes and creates as far as the vault is concerned
+		switch {
+		case ignored(ev.ID) && !ignored(ev.OldID):
+			ev = Event{ID: ev.OldID, Op: Remove, IsDir: ev.IsDir}
+		case ignored(ev.OldID) && !ignored(ev.ID):
+			ev = Event{ID: ev.ID, Op: Create, IsDir: ev.IsDir}
+		}
+	}
+	if ignored(ev.ID) || (ev.OldID != "" && ignored(ev.OldID)) {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	if ev.Op == Rename {
+		// The old ID is gone either way; the new one needs indexing
+		w.pending[ev.OldID] = Event{ID: ev.OldID, Op: Remove, IsDir: ev.IsDir}
+		ev = Event{ID: ev.ID, Op: Create, IsDir: ev.IsDir}
+	}
+	w.pending[ev.ID] = ev
+
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.opts.Debounce, w.flush)
+	} else {
+		w.timer.Reset(w.opts.Debounce)
+	}
+}
+
+func (w *Watcher) flush() {
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = make(map[string]Event)
+	w.mu.Unlock()
+
+	for _, ev := range pending {
+		if err := w.apply(ev); err != nil {
+			log.Printf("Watcher: failed to sync %s (%s): %v", ev.ID, ev.Op, err)
+		}
+	}
+}
+
+func (w *Watcher) apply(ev Event) error {
+	if ev.IsDir {
+		if ev.Op == Remove {
+			if pd, ok := w.indexer.(PrefixDeleter); ok {
+				return pd.DeletePrefix(ev.ID + "/")
+			}
+			return nil
+		}
+		// A folder appeared (created or moved in): index everything in it
+		infos, err := w.source.NotesIn(ev.ID)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			if err := w.apply(Event{ID: info.ID, Op: Create}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if ev.Op == Remove {
+		return w.indexer.Delete(ev.ID)
+	}
+
+	note, err := w.source.Get(ev.ID)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Removed again before the burst settled
+		return w.indexer.Delete(ev.ID)
+	}
+	if err != nil {
+		return err
+	}
+	return w.indexer.Index(note)
+}
+
+// ignored reports whether a path is outside the set of indexable notes:
+// hidden files and directories (.history, .git, editor swap files) and
+// anything that is not a note and not a folder.
+func ignored(id string) bool {
+	if id == "" {
+		return true
+	}
+	for _, seg := range strings.Split(id, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// isNote reports whether a file name is a markdown note.
+func isNote(name string) bool {
+	return path.Ext(name) == ".md"
+}
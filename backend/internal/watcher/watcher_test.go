@@ -0,0 +1,125 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"marko-backend/internal/filesystem"
+	"marko-backend/internal/models"
+)
+
+type fakeIndexer struct {
+	mu      sync.Mutex
+	indexed map[string]string
+}
+
+func (f *fakeIndexer) Index(note models.Note) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.indexed[note.ID] = note.Title
+	return nil
+}
+
+func (f *fakeIndexer) Delete(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.indexed, id)
+	return nil
+}
+
+func (f *fakeIndexer) snapshot() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]string, len(f.indexed))
+	for k, v := range f.indexed {
+		out[k] = v
+	}
+	return out
+}
+
+// waitFor polls until cond holds or the deadline passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func testWatcher(t *testing.T, opts Options) {
+	dir := t.TempDir()
+	idx := &fakeIndexer{indexed: make(map[string]string)}
+	w := New(dir, filesystem.NewStore(dir), idx, opts)
+	if _, err := w.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer w.Close()
+
+	write := func(rel, content string) {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("a.md", "---\ntitle: A\n---\n")
+	write("go/b.md", "---\ntitle: B\n---\n")
+	write("ignored.txt", "not a note")
+	write(".history/a.md/1.md", "hidden")
+	waitFor(t, "creates", func() bool {
+		got := idx.snapshot()
+		return got["a.md"] == "A" && got["go/b.md"] == "B" && len(got) == 2
+	})
+
+	write("a.md", "---\ntitle: A2\n---\n")
+	waitFor(t, "modify", func() bool { return idx.snapshot()["a.md"] == "A2" })
+
+	if err := os.Rename(filepath.Join(dir, "a.md"), filepath.Join(dir, "c.md")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "rename", func() bool {
+		got := idx.snapshot()
+		_, old := got["a.md"]
+		return !old && got["c.md"] == "A2"
+	})
+
+	// Soft delete and restore move notes in and out of a hidden folder
+	write(".trash/1/keep.md", "")
+	if err := os.Rename(filepath.Join(dir, "c.md"), filepath.Join(dir, ".trash", "1", "c.md")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "move to trash", func() bool {
+		_, ok := idx.snapshot()["c.md"]
+		return !ok
+	})
+	if err := os.Rename(filepath.Join(dir, ".trash", "1", "c.md"), filepath.Join(dir, "c.md")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "restore from trash", func() bool { return idx.snapshot()["c.md"] == "A2" })
+
+	if err := os.Remove(filepath.Join(dir, "go", "b.md")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "delete", func() bool {
+		_, ok := idx.snapshot()["go/b.md"]
+		return !ok
+	})
+}
+
+func TestWatcher_Native(t *testing.T) {
+	testWatcher(t, Options{Debounce: 20 * time.Millisecond})
+}
+
+func TestWatcher_Polling(t *testing.T) {
+	testWatcher(t, Options{Debounce: 20 * time.Millisecond, PollInterval: 30 * time.Millisecond, ForcePolling: true})
+}
@@ -2,24 +2,49 @@ import { Note } from '../types';
 
 const API_BASE = 'http://localhost:8080/api/notes';
 
-export async function fetchNotes(): Promise<Note[]> {
-    const res = await fetch(API_BASE);
+export interface NoteList {
+    notes: Note[];
+    total: number;
+    nextCursor?: string;
+}
+
+export async function fetchNotePage(params: Record<string, string> = {}): Promise<NoteList> {
+    const query = new URLSearchParams(params).toString();
+    const res = await fetch(query ? `${API_BASE}?${query}` : API_BASE);
     if (!res.ok) throw new Error('Failed to fetch notes');
     return res.json();
 }
 
+export async function fetchNotes(): Promise<Note[]> {
+    const list = await fetchNotePage();
+    return list.notes;
+}
+
 export async function fetchNote(id: string): Promise<Note> {
     const res = await fetch(`${API_BASE}/${id}`);
     if (!res.ok) throw new Error('Failed to fetch note');
     return res.json();
 }
 
-export async function searchNotes(query: string): Promise<Note[]> {
-  const res = await fetch(`${API_BASE.replace('/api/notes', '/api/search')}?q=${encodeURIComponent(query)}`);
+export interface SearchPage {
+  results: Note[];
+  total: number;
+  nextCursor?: string;
+}
+
+export async function searchNotesPage(query: string, cursor?: string): Promise<SearchPage> {
+  const params = new URLSearchParams({ q: query });
+  if (cursor) params.set('cursor', cursor);
+  const res = await fetch(`${API_BASE.replace('/api/notes', '/api/search')}?${params}`);
   if (!res.ok) throw new Error('Failed to search notes');
   return res.json();
 }
 
+export async function searchNotes(query: string): Promise<Note[]> {
+  const page = await searchNotesPage(query);
+  return page.results;
+}
+
 export async function createNote(content: string): Promise<{ id: string }> {
     const res = await fetch(API_BASE, {
         method: 'POST',